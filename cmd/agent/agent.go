@@ -1,6 +1,10 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -8,113 +12,430 @@ import (
 
 	"github.com/Bessima/metrics-collect/internal/agent"
 	"github.com/Bessima/metrics-collect/internal/common"
+	"github.com/Bessima/metrics-collect/internal/encryption"
 	models "github.com/Bessima/metrics-collect/internal/model"
 )
 
+// названия очередей конвейера, они же суффиксы метрик AgentQueueDepth и AgentQueueDropped
+const (
+	collectQueueName   = "collect"
+	aggregateQueueName = "aggregate"
+	sendQueueName      = "send"
+
+	queueDepthMetric   = "AgentQueueDepth"
+	queueDroppedMetric = "AgentQueueDropped"
+)
+
+// batchRejectedMetric количество пачек, окончательно отклоненных сервером
+const batchRejectedMetric = "AgentBatchesRejected"
+
+// Agent собирает метрики конвейером из ограниченных стадий:
+// коллекторы -> collected -> агрегация -> aggregated -> пачки -> batches -> отправка.
+// На стадии агрегации метрики накапливаются и передаются дальше только раз в интервал отчета.
+// Переполнение каждой очереди обрабатывается по своей политике, глубина очередей
+// и количество отброшенных элементов отправляются вместе с остальными метриками.
 type Agent struct {
-	config *Config
-	client agent.Client
+	config     *Config
+	client     agent.Client
+	grpcClient *agent.GRPCClient
+	spool      *agent.Spool
+	counters   *agent.CounterTracker
+	aggregator *agent.Aggregator
+	batcher    *agent.Batcher
+	collectors []agent.Collector
+
+	collected  *agent.Queue[models.Metrics]
+	aggregated *agent.Queue[models.Metrics]
+	batches    *agent.Queue[[]models.Metrics]
 }
 
 func NewAgent() *Agent {
 	config := InitConfig()
+	if err := config.validateTransport(); err != nil {
+		log.Fatalf("Invalid transport config: %v", err)
+	}
+
+	httpClient := &http.Client{}
+	var tlsConfig *tls.Config
+	if config.tlsEnabled() {
+		clientTLS, err := common.ClientTLSConfig(config.TLSCA, config.TLSCert, config.TLSKey)
+		if err != nil {
+			log.Fatalf("Error loading TLS config: %v", err)
+		}
+		tlsConfig = clientTLS
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		httpClient.Transport = transport
+	}
 
 	client := agent.Client{
 		Domain:     config.getServerAddressWithProtocol(),
-		HTTPClient: &http.Client{},
+		HTTPClient: httpClient,
+		KeyID:      config.KeyID,
+	}
+	if config.CryptoKey != "" {
+		publicKey, err := encryption.LoadPublicKey(config.CryptoKey)
+		if err != nil {
+			log.Fatalf("Error loading crypto key: %v", err)
+		}
+		client.PublicKey = publicKey
 	}
-	return &Agent{
-		config: config,
-		client: client,
+	agentObj := &Agent{
+		config:   config,
+		client:   client,
+		counters: agent.NewCounterTracker(),
 	}
-}
 
-func (a *Agent) workerSendData(metrics <-chan models.Metrics, results chan<- string) {
-	sizeForSending := 10
-	batch := make([]models.Metrics, 0, sizeForSending)
-
-	for metric := range metrics {
-		batch = append(batch, metric)
+	if config.Transport == transportGRPC {
+		grpcClient, err := agent.NewGRPCClient(config.GRPCAddress, config.Key, config.KeyID, tlsConfig)
+		if err != nil {
+			log.Fatalf("Error creating gRPC client: %v", err)
+		}
+		agentObj.grpcClient = grpcClient
+	}
 
-		if len(batch) == sizeForSending {
-			err := a.sendCompressMetrics(batch)
-			if err != nil {
-				results <- fmt.Sprintf("Error sending batch: %v", err)
-			} else {
-				results <- fmt.Sprintf("Batch of %d metrics sent successfully", len(batch))
-			}
-			batch = batch[:0]
+	// сервер с доверенной подсетью проверяет адрес агента по заголовку X-Real-IP
+	if realIP, err := agentObj.detectRealIP(); err != nil {
+		log.Printf("X-Real-IP is disabled: %v", err)
+	} else {
+		agentObj.client.RealIP = realIP
+		if agentObj.grpcClient != nil {
+			agentObj.grpcClient.RealIP = realIP
 		}
 	}
 
-	if len(batch) > 0 {
-		err := a.sendCompressMetrics(batch)
+	if config.SpoolDir != "" {
+		spool, err := agent.NewSpool(config.SpoolDir, config.SpoolMaxSize)
 		if err != nil {
-			results <- fmt.Sprintf("Error sending final batch: %v", err)
+			log.Printf("Spool is disabled: %v", err)
 		} else {
-			results <- fmt.Sprintf("Final batch of %d metrics sent successfully", len(batch))
+			agentObj.spool = spool
+		}
+	}
+
+	// принимаемые извне значения агрегируются за интервал отчета
+	config.setDefaultCollectorInterval(agent.StatsDCollectorName, config.ReportInterval)
+	config.setDefaultCollectorInterval(agent.PushCollectorName, config.ReportInterval)
+
+	aggregator, err := agent.NewAggregator(agent.GaugeAggregation(config.GaugeAggregation), agentObj.counters)
+	if err != nil {
+		log.Fatalf("Error creating aggregator: %v", err)
+	}
+	agentObj.aggregator = aggregator
+
+	batcher, err := agent.NewBatcher(agent.BatchLimits{
+		MaxCount:  config.BatchSize,
+		MaxBytes:  config.BatchMaxBytes,
+		MaxLinger: config.BatchLinger,
+	})
+	if err != nil {
+		log.Fatalf("Error creating batcher: %v", err)
+	}
+	agentObj.batcher = batcher
+
+	if err = agentObj.initQueues(); err != nil {
+		log.Fatalf("Error creating pipeline: %v", err)
+	}
+
+	collectors, err := agent.NewCollectors(
+		config.CollectorConfigs,
+		config.getEnabledCollectors(),
+		time.Duration(config.PoolInterval)*time.Second,
+	)
+	if err != nil {
+		log.Fatalf("Error creating collectors: %v", err)
+	}
+	agentObj.collectors = collectors
+
+	return agentObj
+}
+
+// detectRealIP определяет адрес интерфейса, через который агент обращается к серверу
+func (a *Agent) detectRealIP() (string, error) {
+	address, err := a.config.getDialAddress()
+	if err != nil {
+		return "", err
+	}
+	ip, err := agent.OutboundIP(address)
+	if err != nil {
+		return "", err
+	}
+	return ip.String(), nil
+}
+
+// initQueues создает очереди между стадиями конвейера.
+// Отброшенные приросты счетчиков возвращаются в CounterTracker и будут отправлены со следующим отчетом.
+func (a *Agent) initQueues() error {
+	collectOverflow, err := agent.ParseOverflowPolicy(a.config.CollectOverflow)
+	if err != nil {
+		return err
+	}
+	aggregateOverflow, err := agent.ParseOverflowPolicy(a.config.AggregateOverflow)
+	if err != nil {
+		return err
+	}
+	sendOverflow, err := agent.ParseOverflowPolicy(a.config.SendOverflow)
+	if err != nil {
+		return err
+	}
+
+	a.collected, err = agent.NewQueue(collectQueueName, a.config.CollectQueueSize, collectOverflow, func(metric models.Metrics) {
+		a.countDrop(collectQueueName)
+		if metric.MType == models.Counter && metric.Delta != nil {
+			a.counters.Add(metric.ID, *metric.Delta)
 		}
+	})
+	if err != nil {
+		return err
+	}
+
+	a.aggregated, err = agent.NewQueue(aggregateQueueName, a.config.AggregateQueueSize, aggregateOverflow, func(metric models.Metrics) {
+		a.countDrop(aggregateQueueName)
+		a.counters.Fail([]models.Metrics{metric})
+	})
+	if err != nil {
+		return err
 	}
+
+	a.batches, err = agent.NewQueue(sendQueueName, a.config.SendQueueSize, sendOverflow, func(batch []models.Metrics) {
+		a.countDrop(sendQueueName)
+		a.counters.Fail(batch)
+	})
+	return err
 }
 
-func (a *Agent) Run() {
-	metricsForSend := make(chan models.Metrics, a.config.RateLimit)
-	resultSending := make(chan string, a.config.RateLimit)
-	counter := int64(1)
+func (a *Agent) countDrop(queue string) {
+	a.counters.Add(queueDroppedMetric+"_"+queue, 1)
+}
 
-	defer close(metricsForSend)
-	defer close(resultSending)
+func (a *Agent) Run() {
+	ctx := context.Background()
 
 	for w := 0; w < a.config.RateLimit; w++ {
-		go a.workerSendData(metricsForSend, resultSending)
+		go a.workerSendData(ctx)
 	}
+	go a.batchMetrics(ctx)
+	go a.aggregateMetrics(ctx)
+
+	// пачки, оставшиеся с прошлого запуска
+	go a.replaySpool()
 
-	go func() {
-		for result := range resultSending {
-			log.Printf("Sending result: %s", result)
+	for _, collector := range a.collectors {
+		if listener, ok := collector.(agent.Listener); ok {
+			if err := listener.Start(ctx); err != nil {
+				log.Fatalf("Error starting collector %s: %v", collector.Name(), err)
+			}
 		}
-	}()
+		log.Printf("Running collector %s with interval %s", collector.Name(), collector.Interval())
+		go a.runCollector(ctx, collector)
+	}
 
-	for {
+	ticker := time.NewTicker(time.Duration(a.config.ReportInterval) * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		a.report(ctx)
+	}
+}
 
-		ticker := time.NewTicker(time.Duration(a.config.PoolInterval) * time.Second)
-		done := make(chan bool)
+// runCollector опрашивает коллектор с его собственным интервалом и передает метрики на агрегацию
+func (a *Agent) runCollector(ctx context.Context, collector agent.Collector) {
+	ticker := time.NewTicker(collector.Interval())
+	defer ticker.Stop()
 
-		go func() {
-			for {
-				select {
-				case <-done:
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			collected, err := collector.Collect(ctx)
+			if err != nil {
+				log.Printf("Error collecting metrics from %s: %v", collector.Name(), err)
+			}
+			for _, metric := range collected {
+				if err = a.collected.Push(ctx, metric); err != nil {
 					return
-				case <-ticker.C:
-					go agent.AddBaseMetrics(metricsForSend, counter)
-					go agent.AdditionalMemMetrics(metricsForSend)
-					counter++
 				}
 			}
-		}()
+		}
+	}
+}
+
+// aggregateMetrics накапливает собранные метрики до отчета
+func (a *Agent) aggregateMetrics(ctx context.Context) {
+	for {
+		metric, err := a.collected.Pop(ctx)
+		if err != nil {
+			return
+		}
+		a.aggregator.Add(metric)
+	}
+}
+
+// report передает на отправку метрики, накопленные за интервал отчета, и состояние очередей
+func (a *Agent) report(ctx context.Context) {
+	depths := []models.Metrics{
+		agent.NewGauge(queueDepthMetric+"_"+a.collected.Name(), float64(a.collected.Len())),
+		agent.NewGauge(queueDepthMetric+"_"+a.aggregated.Name(), float64(a.aggregated.Len())),
+		agent.NewGauge(queueDepthMetric+"_"+a.batches.Name(), float64(a.batches.Len())),
+	}
+	for _, metric := range depths {
+		if err := a.aggregated.Push(ctx, metric); err != nil {
+			return
+		}
+	}
+
+	for _, metric := range a.aggregator.Flush() {
+		if err := a.aggregated.Push(ctx, metric); err != nil {
+			a.counters.Fail([]models.Metrics{metric})
+		}
+	}
+}
+
+// batchMetrics собирает метрики в пачки и передает их на отправку, когда достигнуто
+// ограничение по количеству, размеру или времени ожидания
+func (a *Agent) batchMetrics(ctx context.Context) {
+	for {
+		popCtx, cancel := ctx, context.CancelFunc(func() {})
+		if deadline, pending := a.batcher.Deadline(); pending {
+			popCtx, cancel = context.WithDeadline(ctx, deadline)
+		}
+		metric, err := a.aggregated.Pop(popCtx)
+		cancel()
+
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			if !a.pushBatch(ctx, a.batcher.Flush()) {
+				return
+			}
+			continue
+		}
+		if err != nil {
+			a.counters.Fail(a.batcher.Flush())
+			return
+		}
+
+		for _, batch := range a.batcher.Add(metric) {
+			if !a.pushBatch(ctx, batch) {
+				return
+			}
+		}
+	}
+}
 
-		time.Sleep(time.Duration(a.config.ReportInterval) * time.Second)
-		ticker.Stop()
-		done <- true
+func (a *Agent) pushBatch(ctx context.Context, batch []models.Metrics) bool {
+	if err := a.batches.Push(ctx, batch); err != nil {
+		a.counters.Fail(batch)
+		return false
 	}
+	return true
+}
+
+func (a *Agent) workerSendData(ctx context.Context) {
+	for {
+		batch, err := a.batches.Pop(ctx)
+		if err != nil {
+			return
+		}
 
+		if err = a.sendCompressMetrics(batch); err != nil {
+			log.Printf("Error sending batch: %v", err)
+		} else {
+			log.Printf("Batch of %d metrics sent successfully", len(batch))
+		}
+	}
 }
 
 func (a *Agent) sendCompressMetrics(metrics []models.Metrics) error {
 	data, err := agent.CompressJSONMetrics(metrics)
 	if err != nil {
+		a.counters.Fail(metrics)
 		return fmt.Errorf("failed to compress data: %v", err)
 	}
-	hash := ""
-	if a.config.Key != "" {
-		hash = common.GetHashData(data.Bytes(), a.config.Key)
+	batchID, err := common.NewBatchID()
+	if err != nil {
+		a.counters.Fail(metrics)
+		return err
 	}
 
-	err = a.client.SendData(data, hash)
+	// пока в спуле есть пачки, новая встает за ними: иначе более старые значения gauge
+	// из спула дойдут до сервера позже и перезапишут новые
+	if a.spool != nil && a.spool.Len() > 0 {
+		return a.spoolMetrics(batchID, data.Bytes(), metrics)
+	}
+
+	err = a.sendData(batchID, data.Bytes())
+	if errors.Is(err, agent.ErrBatchRejected) {
+		// пачка не сохраняется в спул: ее отклонят снова и она задержит следующие.
+		// Прирост счетчиков вернется в трекер и уйдет со следующей пачкой.
+		a.counters.Fail(metrics)
+		return fmt.Errorf("batch was dropped: %v", err)
+	}
 	if err != nil {
-		return fmt.Errorf("error sending metrics: %s", err)
+		if a.spool == nil {
+			a.counters.Fail(metrics)
+			return fmt.Errorf("error sending metrics: %s", err)
+		}
+		if spoolErr := a.spool.Push(batchID, data.Bytes()); spoolErr != nil {
+			a.counters.Fail(metrics)
+			return fmt.Errorf("error sending metrics: %s, failed to spool batch: %v", err, spoolErr)
+		}
+		// прирост счетчиков будет доставлен вместе с пачкой из спула
+		a.counters.Ack(metrics)
+		return fmt.Errorf("error sending metrics: %s, batch was spooled", err)
 	}
 
+	a.counters.Ack(metrics)
 	log.Printf("metrics in count (%d) sent successfully", len(metrics))
 	return nil
 }
+
+// spoolMetrics ставит пачку в конец спула и отправляет спул по порядку
+func (a *Agent) spoolMetrics(batchID string, data []byte, metrics []models.Metrics) error {
+	if err := a.spool.Push(batchID, data); err != nil {
+		a.counters.Fail(metrics)
+		return fmt.Errorf("failed to spool batch: %v", err)
+	}
+	// прирост счетчиков будет доставлен вместе с пачкой из спула
+	a.counters.Ack(metrics)
+
+	a.replaySpool()
+	if pending := a.spool.Len(); pending > 0 {
+		return fmt.Errorf("batch was spooled, batches in count (%d) are waiting for delivery", pending)
+	}
+	return nil
+}
+
+func (a *Agent) sendData(batchID string, data []byte) error {
+	var err error
+	// пачка сжата в формате спула, gRPC-клиент разбирает ее сам
+	if a.grpcClient != nil {
+		err = a.grpcClient.SendData(data, batchID)
+	} else {
+		hash := ""
+		if a.config.Key != "" {
+			hash = common.GetHashData(data, a.config.Key)
+		}
+		err = a.client.SendData(bytes.NewBuffer(data), hash, batchID)
+	}
+
+	if errors.Is(err, agent.ErrBatchRejected) {
+		a.counters.Add(batchRejectedMetric, 1)
+	}
+	return err
+}
+
+// replaySpool отправляет пачки, сохраненные во время недоступности сервера
+func (a *Agent) replaySpool() {
+	if a.spool == nil {
+		return
+	}
+
+	sent, err := a.spool.Replay(a.sendData)
+	if sent > 0 {
+		log.Printf("Spooled batches in count (%d) sent successfully", sent)
+	}
+	if err != nil {
+		log.Printf("Error sending spooled batches: %v", err)
+	}
+}
@@ -1,9 +1,17 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"net"
+	"net/url"
+	"os"
 	"strings"
+	"time"
 
+	"github.com/Bessima/metrics-collect/internal/agent"
 	"github.com/caarlos0/env"
 )
 
@@ -12,7 +20,50 @@ type Config struct {
 	ReportInterval int64  `env:"REPORT_INTERVAL"`
 	PoolInterval   int64  `env:"POLL_INTERVAL"`
 	Key            string `env:"KEY"`
+	KeyID          string `env:"KEY_ID"`
 	RateLimit      int    `env:"RATE_LIMIT"`
+	SpoolDir       string `env:"SPOOL_DIR"`
+	SpoolMaxSize   int64  `env:"SPOOL_MAX_SIZE"`
+	ConfigPath     string `env:"CONFIG"`
+	// Collectors список включенных коллекторов через запятую, пустой - включены коллекторы по умолчанию
+	Collectors string `env:"COLLECTORS"`
+
+	// размеры очередей между стадиями конвейера и политики их переполнения
+	CollectQueueSize   int    `env:"COLLECT_QUEUE_SIZE"`
+	CollectOverflow    string `env:"COLLECT_OVERFLOW"`
+	AggregateQueueSize int    `env:"AGGREGATE_QUEUE_SIZE"`
+	AggregateOverflow  string `env:"AGGREGATE_OVERFLOW"`
+	SendQueueSize      int    `env:"SEND_QUEUE_SIZE"`
+	SendOverflow       string `env:"SEND_OVERFLOW"`
+
+	// GaugeAggregation какое значение gauge отправляется за интервал отчета
+	GaugeAggregation string `env:"GAUGE_AGGREGATION"`
+
+	// ограничения пачки: пачка отправляется, как только достигнуто любое из них
+	BatchSize     int           `env:"BATCH_SIZE"`
+	BatchMaxBytes int           `env:"BATCH_MAX_BYTES"`
+	BatchLinger   time.Duration `env:"BATCH_LINGER"`
+
+	// Transport способ отправки метрик: http или grpc
+	Transport   string `env:"TRANSPORT"`
+	GRPCAddress string `env:"GRPC_ADDRESS"`
+
+	// CryptoKey путь к открытому ключу RSA сервера, с ним тела запросов шифруются
+	CryptoKey string `env:"CRYPTO_KEY"`
+
+	// TLSCA CA сертификата сервера, остальные CA не принимаются
+	TLSCA string `env:"TLS_CA"`
+	// TLSCert и TLSKey клиентский сертификат агента для mTLS, CN сертификата — имя агента в аудите
+	TLSCert string `env:"TLS_CERT"`
+	TLSKey  string `env:"TLS_KEY"`
+
+	// CollectorConfigs настройки коллекторов из файла конфигурации
+	CollectorConfigs map[string]agent.CollectorConfig
+}
+
+// FileConfig содержимое файла конфигурации агента
+type FileConfig struct {
+	Collectors map[string]agent.CollectorConfig `json:"collectors"`
 }
 
 func InitConfig() *Config {
@@ -24,10 +75,38 @@ func InitConfig() *Config {
 		ReportInterval: flags.reportInterval,
 		PoolInterval:   flags.poolInterval,
 		Key:            flags.key,
+		KeyID:          flags.keyID,
 		RateLimit:      flags.rateLimit,
+		SpoolDir:       flags.spoolDir,
+		SpoolMaxSize:   flags.spoolMaxSize,
+		ConfigPath:     flags.configPath,
+		Collectors:     flags.collectors,
+
+		CollectQueueSize:   flags.collectQueueSize,
+		CollectOverflow:    flags.collectOverflow,
+		AggregateQueueSize: flags.aggregateQueueSize,
+		AggregateOverflow:  flags.aggregateOverflow,
+		SendQueueSize:      flags.sendQueueSize,
+		SendOverflow:       flags.sendOverflow,
+
+		GaugeAggregation: flags.gaugeAggregation,
+
+		BatchSize:     flags.batchSize,
+		BatchMaxBytes: flags.batchMaxBytes,
+		BatchLinger:   flags.batchLinger,
+
+		Transport:   flags.transport,
+		GRPCAddress: flags.grpcAddress,
+
+		CryptoKey: flags.cryptoKey,
+
+		TLSCA:   flags.tlsCA,
+		TLSCert: flags.tlsCert,
+		TLSKey:  flags.tlsKey,
 	}
 
 	cfg.parseEnv()
+	cfg.parseFile()
 
 	return &cfg
 }
@@ -39,12 +118,105 @@ func (cfg *Config) parseEnv() {
 	}
 }
 
+func (cfg *Config) parseFile() {
+	if cfg.ConfigPath == "" {
+		return
+	}
+
+	data, err := os.ReadFile(cfg.ConfigPath)
+	if err != nil {
+		log.Printf("Error reading config file %s: %v", cfg.ConfigPath, err)
+		return
+	}
+
+	fileConfig := FileConfig{}
+	if err = json.Unmarshal(data, &fileConfig); err != nil {
+		log.Printf("Error parsing config file %s: %v", cfg.ConfigPath, err)
+		return
+	}
+	cfg.CollectorConfigs = fileConfig.Collectors
+}
+
+// setDefaultCollectorInterval задает интервал коллектора в секундах, если он не указан в файле конфигурации
+func (cfg *Config) setDefaultCollectorInterval(name string, seconds int64) {
+	if cfg.CollectorConfigs == nil {
+		cfg.CollectorConfigs = make(map[string]agent.CollectorConfig)
+	}
+
+	collectorConfig := cfg.CollectorConfigs[name]
+	if collectorConfig.Interval > 0 {
+		return
+	}
+	collectorConfig.Interval = agent.Duration(time.Duration(seconds) * time.Second)
+	cfg.CollectorConfigs[name] = collectorConfig
+}
+
+func (cfg *Config) getEnabledCollectors() []string {
+	names := make([]string, 0)
+	for _, name := range strings.Split(cfg.Collectors, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// getServerAddressWithProtocol дополняет адрес сервера схемой. С TLS адрес http:// (в том числе
+// адрес по умолчанию) заменяется на https://, иначе сертификаты не использовались бы.
 func (cfg *Config) getServerAddressWithProtocol() string {
 	http := "http://"
 	https := "https://"
 
-	if strings.Contains(cfg.ServerAddress, https) || strings.Contains(cfg.ServerAddress, http) {
+	if strings.HasPrefix(cfg.ServerAddress, https) {
+		return cfg.ServerAddress
+	}
+	if address, found := strings.CutPrefix(cfg.ServerAddress, http); found {
+		if cfg.tlsEnabled() {
+			return https + address
+		}
 		return cfg.ServerAddress
 	}
+	if cfg.tlsEnabled() {
+		return https + cfg.ServerAddress
+	}
 	return http + cfg.ServerAddress
 }
+
+// validateTransport проверяет транспорт. gRPC-транспорт тела не шифрует, поэтому вместе с -crypto-key
+// он не запускается: иначе метрики ушли бы открытыми вопреки настройке.
+func (cfg *Config) validateTransport() error {
+	switch cfg.Transport {
+	case transportHTTP:
+		return nil
+	case transportGRPC:
+		if cfg.CryptoKey != "" {
+			return errors.New("crypto key is not supported by grpc transport, use TLS instead")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown transport %q", cfg.Transport)
+	}
+}
+
+func (cfg *Config) tlsEnabled() bool {
+	return cfg.TLSCA != "" || cfg.TLSCert != "" || cfg.TLSKey != ""
+}
+
+// getDialAddress адрес сервера в виде host:port для выбранного транспорта
+func (cfg *Config) getDialAddress() (string, error) {
+	if cfg.Transport == transportGRPC {
+		return cfg.GRPCAddress, nil
+	}
+
+	serverURL, err := url.Parse(cfg.getServerAddressWithProtocol())
+	if err != nil {
+		return "", fmt.Errorf("failed to parse server address: %v", err)
+	}
+	if serverURL.Port() != "" {
+		return serverURL.Host, nil
+	}
+	if serverURL.Scheme == "https" {
+		return net.JoinHostPort(serverURL.Hostname(), "443"), nil
+	}
+	return net.JoinHostPort(serverURL.Hostname(), "80"), nil
+}
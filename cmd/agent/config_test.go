@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_getServerAddressWithProtocol(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		tlsCA   string
+		want    string
+	}{
+		{name: "default address", address: "http://localhost:8080", want: "http://localhost:8080"},
+		{name: "without scheme", address: "localhost:8080", want: "http://localhost:8080"},
+		{name: "default address with TLS", address: "http://localhost:8080", tlsCA: "ca.pem", want: "https://localhost:8080"},
+		{name: "without scheme with TLS", address: "localhost:8080", tlsCA: "ca.pem", want: "https://localhost:8080"},
+		{name: "https", address: "https://metrics.example:8443", want: "https://metrics.example:8443"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := Config{ServerAddress: test.address, TLSCA: test.tlsCA}
+			assert.Equal(t, test.want, cfg.getServerAddressWithProtocol())
+		})
+	}
+}
+
+func TestConfig_validateTransport(t *testing.T) {
+	assert.NoError(t, (&Config{Transport: transportHTTP, CryptoKey: "public.pem"}).validateTransport())
+	assert.NoError(t, (&Config{Transport: transportGRPC}).validateTransport())
+
+	// gRPC не шифрует тела, поэтому не запускается с ключом шифрования
+	assert.Error(t, (&Config{Transport: transportGRPC, CryptoKey: "public.pem"}).validateTransport())
+	assert.Error(t, (&Config{Transport: "udp"}).validateTransport())
+}
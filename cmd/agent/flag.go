@@ -2,18 +2,61 @@ package main
 
 import (
 	"flag"
+	"time"
+
+	"github.com/Bessima/metrics-collect/internal/agent"
 )
 
 const defaultPollInterval = 2
 const defaultReportInterval = 10
 const defaultRateLimit = 10
+const defaultSpoolMaxSize = 10 * 1024 * 1024
+const defaultCollectQueueSize = 1000
+const defaultAggregateQueueSize = 1000
+const defaultSendQueueSize = 100
+const defaultBatchSize = 10
+const defaultBatchMaxBytes = 1024 * 1024
+const defaultBatchLinger = time.Second
+const defaultGRPCAddress = "localhost:3200"
+
+const (
+	transportHTTP = "http"
+	transportGRPC = "grpc"
+)
 
 type AgentFlags struct {
 	serverAddress  string
 	poolInterval   int64
 	reportInterval int64
 	key            string
+	keyID          string
 	rateLimit      int
+	spoolDir       string
+	spoolMaxSize   int64
+	configPath     string
+	collectors     string
+
+	collectQueueSize   int
+	collectOverflow    string
+	aggregateQueueSize int
+	aggregateOverflow  string
+	sendQueueSize      int
+	sendOverflow       string
+
+	gaugeAggregation string
+
+	batchSize     int
+	batchMaxBytes int
+	batchLinger   time.Duration
+
+	transport   string
+	grpcAddress string
+
+	cryptoKey string
+
+	tlsCA   string
+	tlsCert string
+	tlsKey  string
 }
 
 func (f *AgentFlags) Init() {
@@ -21,7 +64,28 @@ func (f *AgentFlags) Init() {
 	flag.Int64Var(&f.poolInterval, "p", defaultPollInterval, "poll interval")
 	flag.Int64Var(&f.reportInterval, "r", defaultReportInterval, "report interval")
 	flag.StringVar(&f.key, "k", "", "key for hash")
+	flag.StringVar(&f.keyID, "key-id", "", "id of hash key in server key ring")
 	flag.IntVar(&f.rateLimit, "l", defaultRateLimit, "rate limit for pool")
+	flag.StringVar(&f.spoolDir, "spool-dir", "", "directory for unsent batches")
+	flag.Int64Var(&f.spoolMaxSize, "spool-max-size", defaultSpoolMaxSize, "max size of spool directory in bytes")
+	flag.StringVar(&f.configPath, "config", "", "path to JSON config file")
+	flag.StringVar(&f.collectors, "collectors", "", "comma separated list of enabled collectors")
+	flag.IntVar(&f.collectQueueSize, "collect-queue-size", defaultCollectQueueSize, "size of queue between collectors and aggregation")
+	flag.StringVar(&f.collectOverflow, "collect-overflow", string(agent.OverflowDropOldest), "overflow policy of collect queue: block, drop_oldest, drop_newest")
+	flag.IntVar(&f.aggregateQueueSize, "aggregate-queue-size", defaultAggregateQueueSize, "size of queue between aggregation and batching")
+	flag.StringVar(&f.aggregateOverflow, "aggregate-overflow", string(agent.OverflowBlock), "overflow policy of aggregate queue: block, drop_oldest, drop_newest")
+	flag.IntVar(&f.sendQueueSize, "send-queue-size", defaultSendQueueSize, "size of queue of batches waiting for sending")
+	flag.StringVar(&f.sendOverflow, "send-overflow", string(agent.OverflowBlock), "overflow policy of send queue: block, drop_oldest, drop_newest")
+	flag.StringVar(&f.gaugeAggregation, "gauge-aggregation", string(agent.GaugeLast), "value of gauge sent per report interval: last, min, max, avg")
+	flag.IntVar(&f.batchSize, "batch-size", defaultBatchSize, "max count of metrics in batch")
+	flag.IntVar(&f.batchMaxBytes, "batch-max-bytes", defaultBatchMaxBytes, "max size of batch in bytes before compression")
+	flag.DurationVar(&f.batchLinger, "batch-linger", defaultBatchLinger, "max time a batch waits for more metrics")
+	flag.StringVar(&f.transport, "transport", transportHTTP, "transport for sending metrics: http, grpc")
+	flag.StringVar(&f.grpcAddress, "grpc-address", defaultGRPCAddress, "address and port of gRPC server")
+	flag.StringVar(&f.cryptoKey, "crypto-key", "", "path to RSA public key of server for encrypting metrics")
+	flag.StringVar(&f.tlsCA, "tls-ca", "", "path to CA of server certificate")
+	flag.StringVar(&f.tlsCert, "tls-cert", "", "path to TLS client certificate of agent")
+	flag.StringVar(&f.tlsKey, "tls-key", "", "path to TLS client key of agent")
 
 	flag.Parse()
 }
@@ -2,12 +2,16 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
 	"os/signal"
 	"syscall"
 
+	"github.com/Bessima/metrics-collect/internal/common"
 	"github.com/Bessima/metrics-collect/internal/config"
+	"github.com/Bessima/metrics-collect/internal/encryption"
+	"github.com/Bessima/metrics-collect/internal/keyring"
 	"github.com/Bessima/metrics-collect/internal/middlewares/logger"
 	"github.com/Bessima/metrics-collect/internal/repository"
 	"github.com/Bessima/metrics-collect/internal/service"
@@ -66,7 +70,36 @@ func run() error {
 
 	}
 
+	var tlsConfig *tls.Config
+	if conf.TLSCert != "" {
+		serverTLS, err := common.ServerTLSConfig(conf.TLSCert, conf.TLSKey, conf.TLSClientCA)
+		if err != nil {
+			return err
+		}
+		tlsConfig = serverTLS
+	}
+
+	trustedSubnets, err := common.ParseSubnets(conf.TrustedSubnet)
+	if err != nil {
+		return err
+	}
+
+	keys, err := keyring.Load(conf.KeyRing, conf.KeyHash)
+	if err != nil {
+		return err
+	}
+
 	serverService := service.NewServerService(rootCtx, conf.Address, conf.KeyHash, app.storageRepository)
+	serverService.SetKeyRing(keys)
+	serverService.SetTLSConfig(tlsConfig)
+	serverService.SetTrustedSubnets(trustedSubnets, conf.TrustProxy)
+	if conf.CryptoKey != "" {
+		privateKey, err := encryption.LoadPrivateKey(conf.CryptoKey)
+		if err != nil {
+			return err
+		}
+		serverService.SetPrivateKey(privateKey)
+	}
 	serverService.SetRouter(conf.StoreInterval, app.metricsFromFile, &event)
 
 	saveCtx, saveCancel := context.WithCancel(rootCtx)
@@ -77,15 +110,29 @@ func run() error {
 	logger.Log.Info("Running Server on", zap.String("address", conf.Address))
 	go serverService.RunServer(&serverErr)
 
+	var grpcService *service.GRPCService
+	grpcErr := make(chan error, 1)
+	if conf.GRPCAddress != "" {
+		grpcService = service.NewGRPCService(conf.GRPCAddress, keys, trustedSubnets, conf.TrustProxy, tlsConfig, app.storageRepository)
+		grpcService.SetHandlers(conf.StoreInterval, app.metricsFromFile, &event)
+
+		logger.Log.Info("Running gRPC Server on", zap.String("address", conf.GRPCAddress))
+		go grpcService.RunServer(&grpcErr)
+	}
+
 	// Ждем сигнал завершения или ошибку сервера
-	var err error
 	select {
 	case <-rootCtx.Done():
 		logger.Log.Info("Received shutdown signal, shutting down.")
 	case err = <-serverErr:
 		logger.Log.Error("Server error", zap.Error(err))
+	case err = <-grpcErr:
+		logger.Log.Error("gRPC server error", zap.Error(err))
 	}
 
+	if grpcService != nil {
+		grpcService.Shutdown()
+	}
 	if shutdownErr := serverService.Shutdown(); shutdownErr != nil {
 		logger.Log.Error("Server shutdown error", zap.Error(shutdownErr))
 	}
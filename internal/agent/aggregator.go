@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+)
+
+// GaugeAggregation какое значение gauge отправляется за интервал отчета
+type GaugeAggregation string
+
+const (
+	// GaugeLast последнее собранное значение
+	GaugeLast GaugeAggregation = "last"
+	GaugeMin  GaugeAggregation = "min"
+	GaugeMax  GaugeAggregation = "max"
+	// GaugeAvg среднее всех значений за интервал
+	GaugeAvg GaugeAggregation = "avg"
+)
+
+// ParseGaugeAggregation проверяет название способа агрегации gauge
+func ParseGaugeAggregation(value string) (GaugeAggregation, error) {
+	switch aggregation := GaugeAggregation(value); aggregation {
+	case GaugeLast, GaugeMin, GaugeMax, GaugeAvg:
+		return aggregation, nil
+	default:
+		return "", fmt.Errorf("unknown gauge aggregation %q", value)
+	}
+}
+
+// aggregateKey метрики с одинаковым именем, но разным типом агрегируются отдельно
+type aggregateKey struct {
+	id    string
+	mType string
+}
+
+// gaugeAggregate значения gauge, собранные за интервал отчета
+type gaugeAggregate struct {
+	last  float64
+	min   float64
+	max   float64
+	sum   float64
+	count int64
+}
+
+// Aggregator накапливает метрики между отчетами по ключу (ID, MType).
+// Приросты счетчиков суммируются в CounterTracker, поэтому прирост неудачной отправки не теряется.
+// Для gauge отправляется одно значение за интервал: последнее, минимальное, максимальное или среднее.
+type Aggregator struct {
+	aggregation GaugeAggregation
+	counters    *CounterTracker
+
+	mutex  sync.Mutex
+	gauges map[aggregateKey]*gaugeAggregate
+}
+
+func NewAggregator(aggregation GaugeAggregation, counters *CounterTracker) (*Aggregator, error) {
+	if _, err := ParseGaugeAggregation(string(aggregation)); err != nil {
+		return nil, err
+	}
+
+	return &Aggregator{
+		aggregation: aggregation,
+		counters:    counters,
+		gauges:      make(map[aggregateKey]*gaugeAggregate),
+	}, nil
+}
+
+// Add учитывает собранную метрику, метрики без значения пропускаются
+func (a *Aggregator) Add(metric models.Metrics) {
+	switch {
+	case metric.MType == models.Counter && metric.Delta != nil:
+		a.counters.Add(metric.ID, *metric.Delta)
+	case metric.MType == models.Gauge && metric.Value != nil:
+		a.addGauge(aggregateKey{id: metric.ID, mType: metric.MType}, *metric.Value)
+	}
+}
+
+func (a *Aggregator) addGauge(key aggregateKey, value float64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	aggregate, exists := a.gauges[key]
+	if !exists {
+		a.gauges[key] = &gaugeAggregate{last: value, min: value, max: value, sum: value, count: 1}
+		return
+	}
+
+	aggregate.last = value
+	aggregate.min = min(aggregate.min, value)
+	aggregate.max = max(aggregate.max, value)
+	aggregate.sum += value
+	aggregate.count++
+}
+
+// Flush возвращает накопленные за интервал метрики и начинает новый интервал.
+// Gauge, которые не собирались в этом интервале, повторно не отправляются.
+func (a *Aggregator) Flush() []models.Metrics {
+	a.mutex.Lock()
+	gauges := a.gauges
+	a.gauges = make(map[aggregateKey]*gaugeAggregate, len(gauges))
+	a.mutex.Unlock()
+
+	keys := make([]aggregateKey, 0, len(gauges))
+	for key := range gauges {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].id < keys[j].id
+	})
+
+	metrics := make([]models.Metrics, 0, len(keys))
+	for _, key := range keys {
+		metrics = append(metrics, NewGauge(key.id, gauges[key].value(a.aggregation)))
+	}
+	// счетчики отправляются приростом с момента последней успешной отправки
+	return append(metrics, a.counters.Take()...)
+}
+
+func (aggregate *gaugeAggregate) value(aggregation GaugeAggregation) float64 {
+	switch aggregation {
+	case GaugeMin:
+		return aggregate.min
+	case GaugeMax:
+		return aggregate.max
+	case GaugeAvg:
+		return aggregate.sum / float64(aggregate.count)
+	default:
+		return aggregate.last
+	}
+}
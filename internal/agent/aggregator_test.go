@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"testing"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregator_Gauges(t *testing.T) {
+	tests := []struct {
+		aggregation GaugeAggregation
+		want        float64
+	}{
+		{GaugeLast, 2},
+		{GaugeMin, 1},
+		{GaugeMax, 6},
+		{GaugeAvg, 3},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.aggregation), func(t *testing.T) {
+			aggregator, err := NewAggregator(test.aggregation, NewCounterTracker())
+			require.NoError(t, err)
+
+			for _, value := range []float64{1, 6, 2} {
+				aggregator.Add(NewGauge("Alloc", value))
+			}
+
+			metrics := aggregator.Flush()
+			require.Len(t, metrics, 1)
+			assert.Equal(t, test.want, gaugeValues(metrics)["Alloc"])
+		})
+	}
+}
+
+func TestAggregator_SumsCountersAndResetsInterval(t *testing.T) {
+	aggregator, err := NewAggregator(GaugeLast, NewCounterTracker())
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		aggregator.Add(NewCounter(CounterPollCountMetric, 1))
+		aggregator.Add(NewGauge(GaugeRandomMetric, float64(i)))
+	}
+	// одинаковое имя с другим типом агрегируется отдельно
+	aggregator.Add(NewGauge(CounterPollCountMetric, 7))
+	aggregator.Add(models.Metrics{ID: "Empty", MType: models.Gauge})
+
+	metrics := aggregator.Flush()
+	require.Len(t, metrics, 3)
+	assert.Equal(t, int64(5), counterValues(metrics)[CounterPollCountMetric])
+	assert.Equal(t, 4.0, gaugeValues(metrics)[GaugeRandomMetric])
+	assert.Equal(t, 7.0, gaugeValues(metrics)[CounterPollCountMetric])
+
+	// прирост не подтвержден, gauge за новый интервал не собирались
+	metrics = aggregator.Flush()
+	assert.Empty(t, metrics)
+}
+
+func TestParseGaugeAggregation(t *testing.T) {
+	_, err := ParseGaugeAggregation("median")
+	assert.Error(t, err)
+
+	_, err = NewAggregator("", NewCounterTracker())
+	assert.Error(t, err)
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+)
+
+// BatchLimits ограничения пачки метрик: пачка отправляется, как только достигнут любой из них
+type BatchLimits struct {
+	// MaxCount количество метрик в пачке
+	MaxCount int
+	// MaxBytes размер пачки в JSON до сжатия
+	MaxBytes int
+	// MaxLinger сколько пачка может ждать после добавления первой метрики
+	MaxLinger time.Duration
+}
+
+// Batcher собирает метрики в пачки с учетом BatchLimits.
+// Время ожидания Batcher не отслеживает сам: вызывающий ждет до Deadline и забирает пачку через Flush.
+type Batcher struct {
+	limits BatchLimits
+	now    func() time.Time
+
+	metrics  []models.Metrics
+	size     int
+	deadline time.Time
+}
+
+func NewBatcher(limits BatchLimits) (*Batcher, error) {
+	if limits.MaxCount <= 0 {
+		return nil, errors.New("batch max count must be positive")
+	}
+	if limits.MaxBytes <= 0 {
+		return nil, errors.New("batch max bytes must be positive")
+	}
+	if limits.MaxLinger <= 0 {
+		return nil, errors.New("batch max linger must be positive")
+	}
+
+	return &Batcher{limits: limits, now: time.Now}, nil
+}
+
+// Add добавляет метрику и возвращает пачки, достигшие ограничений.
+// Метрика, которая не помещается в MaxBytes даже одна, отправляется отдельной пачкой.
+func (b *Batcher) Add(metric models.Metrics) [][]models.Metrics {
+	ready := make([][]models.Metrics, 0)
+
+	size := metricJSONSize(metric)
+	if len(b.metrics) > 0 && b.size+size > b.limits.MaxBytes {
+		ready = append(ready, b.Flush())
+	}
+
+	if len(b.metrics) == 0 {
+		b.deadline = b.now().Add(b.limits.MaxLinger)
+		// скобки массива
+		b.size = 2
+	} else {
+		// запятая между элементами
+		b.size++
+	}
+	b.metrics = append(b.metrics, metric)
+	b.size += size
+
+	if len(b.metrics) >= b.limits.MaxCount || b.size >= b.limits.MaxBytes {
+		ready = append(ready, b.Flush())
+	}
+	return ready
+}
+
+// Deadline время, когда текущую пачку нужно отправить, false — если пачка пуста
+func (b *Batcher) Deadline() (time.Time, bool) {
+	return b.deadline, len(b.metrics) > 0
+}
+
+// Flush возвращает текущую пачку, даже если ограничения не достигнуты
+func (b *Batcher) Flush() []models.Metrics {
+	batch := b.metrics
+	b.metrics = nil
+	b.size = 0
+	b.deadline = time.Time{}
+	return batch
+}
+
+func metricJSONSize(metric models.Metrics) int {
+	data, err := json.Marshal(metric)
+	if err != nil {
+		// такую метрику не получится отправить, ошибку вернет сжатие пачки
+		return 0
+	}
+	return len(data)
+}
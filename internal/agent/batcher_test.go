@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatcher_MaxCount(t *testing.T) {
+	batcher, err := NewBatcher(BatchLimits{MaxCount: 3, MaxBytes: 1024 * 1024, MaxLinger: time.Second})
+	require.NoError(t, err)
+
+	ready := make([][]models.Metrics, 0)
+	for i := 0; i < 7; i++ {
+		ready = append(ready, batcher.Add(NewGauge(fmt.Sprintf("Gauge%d", i), float64(i)))...)
+	}
+
+	require.Len(t, ready, 2)
+	assert.Len(t, ready[0], 3)
+	assert.Len(t, ready[1], 3)
+	assert.Len(t, batcher.Flush(), 1)
+}
+
+func TestBatcher_MaxBytes(t *testing.T) {
+	metric := NewGauge("Alloc", 1)
+	data, err := json.Marshal([]models.Metrics{metric, metric})
+	require.NoError(t, err)
+
+	// в пачку помещаются ровно две метрики
+	batcher, err := NewBatcher(BatchLimits{MaxCount: 100, MaxBytes: len(data) + 1, MaxLinger: time.Second})
+	require.NoError(t, err)
+
+	assert.Empty(t, batcher.Add(metric))
+	assert.Empty(t, batcher.Add(metric))
+	ready := batcher.Add(metric)
+	require.Len(t, ready, 1)
+	assert.Len(t, ready[0], 2)
+
+	batch := batcher.Flush()
+	encoded, err := json.Marshal(batch)
+	require.NoError(t, err)
+	assert.Len(t, batch, 1)
+	assert.Less(t, len(encoded), len(data))
+}
+
+func TestBatcher_OversizedMetric(t *testing.T) {
+	batcher, err := NewBatcher(BatchLimits{MaxCount: 100, MaxBytes: 10, MaxLinger: time.Second})
+	require.NoError(t, err)
+
+	ready := batcher.Add(NewGauge("Alloc", 1))
+	require.Len(t, ready, 1)
+	assert.Len(t, ready[0], 1)
+
+	_, pending := batcher.Deadline()
+	assert.False(t, pending)
+}
+
+func TestBatcher_Deadline(t *testing.T) {
+	batcher, err := NewBatcher(BatchLimits{MaxCount: 100, MaxBytes: 1024, MaxLinger: time.Second})
+	require.NoError(t, err)
+	started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	batcher.now = func() time.Time { return started }
+
+	_, pending := batcher.Deadline()
+	assert.False(t, pending)
+
+	batcher.Add(NewGauge("Alloc", 1))
+	batcher.now = func() time.Time { return started.Add(500 * time.Millisecond) }
+	batcher.Add(NewGauge("Alloc", 2))
+
+	// срок отсчитывается от первой метрики пачки
+	deadline, pending := batcher.Deadline()
+	assert.True(t, pending)
+	assert.Equal(t, started.Add(time.Second), deadline)
+
+	assert.Len(t, batcher.Flush(), 2)
+	_, pending = batcher.Deadline()
+	assert.False(t, pending)
+}
+
+func TestNewBatcher_InvalidLimits(t *testing.T) {
+	invalid := []BatchLimits{
+		{MaxBytes: 1, MaxLinger: time.Second},
+		{MaxCount: 1, MaxLinger: time.Second},
+		{MaxCount: 1, MaxBytes: 1},
+	}
+	for _, limits := range invalid {
+		_, err := NewBatcher(limits)
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,303 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+)
+
+const CgroupCollectorName = "cgroup"
+
+const (
+	defaultCgroupRoot = "/sys/fs/cgroup"
+	selfCgroupFile    = "/proc/self/cgroup"
+)
+
+// CgroupOptions настройки коллектора cgroup v2
+type CgroupOptions struct {
+	// Root точка монтирования cgroup2
+	Root string `json:"root"`
+	// Path путь к группе относительно Root, по умолчанию группа самого агента
+	Path string `json:"path,omitempty"`
+}
+
+func defaultCgroupOptions() CgroupOptions {
+	return CgroupOptions{Root: defaultCgroupRoot}
+}
+
+func init() {
+	RegisterCollector(CgroupCollectorName, func(interval time.Duration, options json.RawMessage) (Collector, error) {
+		cgroupOptions := defaultCgroupOptions()
+		if err := decodeOptions(options, &cgroupOptions); err != nil {
+			return nil, err
+		}
+		return NewCgroupCollector(interval, cgroupOptions, selfCgroupFile)
+	}, false)
+}
+
+// cgroupCPUCounters поля cpu.stat, которые отправляются счетчиками
+var cgroupCPUCounters = []struct {
+	field  string
+	metric string
+}{
+	{"usage_usec", "CgroupCPUUsageUsec"},
+	{"user_usec", "CgroupCPUUserUsec"},
+	{"system_usec", "CgroupCPUSystemUsec"},
+	{"nr_periods", "CgroupCPUPeriods"},
+	{"nr_throttled", "CgroupCPUThrottledPeriods"},
+	{"throttled_usec", "CgroupCPUThrottledUsec"},
+}
+
+// cgroupIOCounters поля io.stat, которые отправляются счетчиками для каждого устройства
+var cgroupIOCounters = []struct {
+	field  string
+	metric string
+}{
+	{"rbytes", "CgroupIOReadBytes"},
+	{"wbytes", "CgroupIOWriteBytes"},
+	{"rios", "CgroupIOReadOps"},
+	{"wios", "CgroupIOWriteOps"},
+}
+
+// CgroupCollector собирает потребление ресурсов группой cgroup v2.
+// В контейнере показатели хоста из mem.VirtualMemory не отражают лимиты, поэтому значения читаются из cgroupfs.
+type CgroupCollector struct {
+	interval time.Duration
+	dir      string
+	counters *cumulativeCounters
+}
+
+// NewCgroupCollector создает коллектор для группы options.Path или, если путь не задан,
+// для группы текущего процесса из selfCgroup (обычно /proc/self/cgroup).
+func NewCgroupCollector(interval time.Duration, options CgroupOptions, selfCgroup string) (*CgroupCollector, error) {
+	root := options.Root
+	if root == "" {
+		root = defaultCgroupRoot
+	}
+
+	cgroupPath := options.Path
+	if cgroupPath == "" {
+		var err error
+		if cgroupPath, err = ownCgroupPath(selfCgroup); err != nil {
+			return nil, err
+		}
+	}
+
+	dir := filepath.Join(root, filepath.Clean("/"+cgroupPath))
+	if _, err := os.Stat(filepath.Join(dir, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("%s is not a cgroup v2 directory: %v", dir, err)
+	}
+
+	return &CgroupCollector{
+		interval: interval,
+		dir:      dir,
+		counters: newCumulativeCounters(),
+	}, nil
+}
+
+func (c *CgroupCollector) Name() string {
+	return CgroupCollectorName
+}
+
+func (c *CgroupCollector) Interval() time.Duration {
+	return c.interval
+}
+
+func (c *CgroupCollector) Collect(_ context.Context) ([]models.Metrics, error) {
+	metrics := make([]models.Metrics, 0)
+	errs := make([]error, 0)
+
+	gauges := []struct {
+		file   string
+		metric string
+	}{
+		{"memory.current", "CgroupMemoryCurrent"},
+		{"memory.max", "CgroupMemoryMax"},
+		{"pids.current", "CgroupPidsCurrent"},
+		{"pids.max", "CgroupPidsMax"},
+	}
+	for _, gauge := range gauges {
+		value, ok, err := c.readValue(gauge.file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ok {
+			metrics = append(metrics, NewGauge(gauge.metric, float64(value)))
+		}
+	}
+
+	cpuMetrics, err := c.collectCPU()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	metrics = append(metrics, cpuMetrics...)
+
+	ioMetrics, err := c.collectIO()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	metrics = append(metrics, ioMetrics...)
+
+	return metrics, errors.Join(errs...)
+}
+
+func (c *CgroupCollector) collectCPU() ([]models.Metrics, error) {
+	data, err := c.readFile("cpu.stat")
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	fields, err := parseFlatKeyed(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing cpu.stat: %v", err)
+	}
+
+	metrics := make([]models.Metrics, 0, len(cgroupCPUCounters))
+	for _, counter := range cgroupCPUCounters {
+		value, exists := fields[counter.field]
+		if !exists {
+			continue
+		}
+		if delta, ok := c.counters.delta(counter.metric, value); ok {
+			metrics = append(metrics, NewCounter(counter.metric, delta))
+		}
+	}
+	return metrics, nil
+}
+
+func (c *CgroupCollector) collectIO() ([]models.Metrics, error) {
+	data, err := c.readFile("io.stat")
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	devices, err := parseIOStat(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing io.stat: %v", err)
+	}
+
+	names := make([]string, 0, len(devices))
+	for device := range devices {
+		names = append(names, device)
+	}
+	sort.Strings(names)
+
+	metrics := make([]models.Metrics, 0, len(names)*len(cgroupIOCounters))
+	for _, device := range names {
+		for _, counter := range cgroupIOCounters {
+			value, exists := devices[device][counter.field]
+			if !exists {
+				continue
+			}
+			name := labeledName(counter.metric, device)
+			if delta, ok := c.counters.delta(name, value); ok {
+				metrics = append(metrics, NewCounter(name, delta))
+			}
+		}
+	}
+	return metrics, nil
+}
+
+// readFile читает файл группы, отсутствующий файл (контроллер не включен) не считается ошибкой
+func (c *CgroupCollector) readFile(name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(c.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", name, err)
+	}
+	return data, nil
+}
+
+// readValue читает файл с одним числом. Значение "max" означает отсутствие лимита и не отправляется.
+func (c *CgroupCollector) readValue(name string) (uint64, bool, error) {
+	data, err := c.readFile(name)
+	if err != nil || data == nil {
+		return 0, false, err
+	}
+
+	raw := strings.TrimSpace(string(data))
+	if raw == "max" {
+		return 0, false, nil
+	}
+	value, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid value in %s: %v", name, err)
+	}
+	return value, true, nil
+}
+
+// ownCgroupPath возвращает путь группы cgroup v2 из файла формата /proc/self/cgroup ("0::/path")
+func ownCgroupPath(selfCgroup string) (string, error) {
+	data, err := os.ReadFile(selfCgroup)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %v", selfCgroup, err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if cgroupPath, found := strings.CutPrefix(scanner.Text(), "0::"); found {
+			return cgroupPath, nil
+		}
+	}
+	return "", fmt.Errorf("cgroup v2 entry not found in %s", selfCgroup)
+}
+
+// parseFlatKeyed разбирает файлы вида "key value" по одной паре на строку
+func parseFlatKeyed(data []byte) (map[string]uint64, error) {
+	values := make(map[string]uint64)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s: %v", fields[0], err)
+		}
+		values[fields[0]] = value
+	}
+	return values, nil
+}
+
+// parseIOStat разбирает io.stat: "8:0 rbytes=1 wbytes=2 rios=3 wios=4 dbytes=0 dios=0"
+func parseIOStat(data []byte) (map[string]map[string]uint64, error) {
+	devices := make(map[string]map[string]uint64)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		values := make(map[string]uint64, len(fields)-1)
+		for _, field := range fields[1:] {
+			key, raw, found := strings.Cut(field, "=")
+			if !found {
+				return nil, fmt.Errorf("invalid field %q", field)
+			}
+			value, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of %s: %v", key, err)
+			}
+			values[key] = value
+		}
+		devices[fields[0]] = values
+	}
+	return devices, nil
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCgroupFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+}
+
+func TestCgroupCollector_Collect(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "system.slice", "agent.service")
+	writeCgroupFiles(t, dir, map[string]string{
+		"cgroup.controllers": "cpu io memory pids\n",
+		"memory.current":     "1048576\n",
+		"memory.max":         "4194304\n",
+		"pids.current":       "7\n",
+		"pids.max":           "max\n",
+		"cpu.stat":           "usage_usec 1000\nuser_usec 600\nsystem_usec 400\nnr_periods 10\nnr_throttled 1\nthrottled_usec 50\n",
+		"io.stat":            "8:0 rbytes=4096 wbytes=8192 rios=1 wios=2 dbytes=0 dios=0\n",
+	})
+
+	selfCgroup := filepath.Join(t.TempDir(), "cgroup")
+	require.NoError(t, os.WriteFile(selfCgroup, []byte("0::/system.slice/agent.service\n"), 0o644))
+
+	collector, err := NewCgroupCollector(time.Second, CgroupOptions{Root: root}, selfCgroup)
+	require.NoError(t, err)
+
+	first, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+
+	gauges := gaugeValues(first)
+	assert.Equal(t, 1048576.0, gauges["CgroupMemoryCurrent"])
+	assert.Equal(t, 4194304.0, gauges["CgroupMemoryMax"])
+	assert.Equal(t, 7.0, gauges["CgroupPidsCurrent"])
+	// без лимита значение не отправляется
+	assert.NotContains(t, gauges, "CgroupPidsMax")
+	assert.Empty(t, counterValues(first))
+
+	writeCgroupFiles(t, dir, map[string]string{
+		"cpu.stat": "usage_usec 1500\nuser_usec 900\nsystem_usec 600\nnr_periods 12\nnr_throttled 2\nthrottled_usec 80\n",
+		"io.stat":  "8:0 rbytes=5096 wbytes=8192 rios=3 wios=2 dbytes=0 dios=0\n",
+	})
+
+	second, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+
+	counters := counterValues(second)
+	assert.Equal(t, int64(500), counters["CgroupCPUUsageUsec"])
+	assert.Equal(t, int64(300), counters["CgroupCPUUserUsec"])
+	assert.Equal(t, int64(1), counters["CgroupCPUThrottledPeriods"])
+	assert.Equal(t, int64(30), counters["CgroupCPUThrottledUsec"])
+	assert.Equal(t, int64(1000), counters["CgroupIOReadBytes_8_0"])
+	assert.Equal(t, int64(0), counters["CgroupIOWriteBytes_8_0"])
+	assert.Equal(t, int64(2), counters["CgroupIOReadOps_8_0"])
+}
+
+func TestCgroupCollector_MissingControllers(t *testing.T) {
+	root := t.TempDir()
+	writeCgroupFiles(t, root, map[string]string{
+		"cgroup.controllers": "memory\n",
+		"memory.current":     "100\n",
+	})
+
+	collector, err := NewCgroupCollector(time.Second, CgroupOptions{Root: root, Path: "/"}, "")
+	require.NoError(t, err)
+
+	metrics, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"CgroupMemoryCurrent": 100}, gaugeValues(metrics))
+}
+
+func TestNewCgroupCollector_Errors(t *testing.T) {
+	root := t.TempDir()
+
+	_, err := NewCgroupCollector(time.Second, CgroupOptions{Root: root, Path: "/missing"}, "")
+	assert.Error(t, err)
+
+	selfCgroup := filepath.Join(t.TempDir(), "cgroup")
+	require.NoError(t, os.WriteFile(selfCgroup, []byte("12:memory:/docker/abc\n"), 0o644))
+	_, err = NewCgroupCollector(time.Second, CgroupOptions{Root: root}, selfCgroup)
+	assert.Error(t, err)
+}
@@ -2,7 +2,9 @@ package agent
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/rsa"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,10 +12,13 @@ import (
 	"go.uber.org/zap"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"strconv"
 
 	"github.com/Bessima/metrics-collect/internal/common"
+	"github.com/Bessima/metrics-collect/internal/encryption"
+	"github.com/Bessima/metrics-collect/internal/keyring"
 	models "github.com/Bessima/metrics-collect/internal/model"
 	"github.com/Bessima/metrics-collect/internal/repository"
 	"github.com/Bessima/metrics-collect/internal/retry"
@@ -22,12 +27,43 @@ import (
 type Client struct {
 	Domain     string
 	HTTPClient *http.Client
+	// PublicKey открытый ключ сервера, с ним тело SendData отправляется зашифрованным
+	PublicKey *rsa.PublicKey
+	// RealIP адрес агента для заголовка X-Real-IP
+	RealIP string
+	// KeyID идентификатор ключа, которым подписаны тела SendData
+	KeyID string
+}
+
+// OutboundIP возвращает адрес интерфейса, через который агент подключается к серверу address (host:port).
+// Соединение UDP только выбирает маршрут, пакеты не отправляются.
+func OutboundIP(address string) (net.IP, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect outbound address: %v", err)
+	}
+	defer conn.Close()
+
+	localAddr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return nil, errors.New("failed to detect outbound address")
+	}
+	return localAddr.IP, nil
 }
 
 func (client *Client) SendMetric(typeMetric string, name string, value string) error {
 
 	postURL := fmt.Sprintf("%s/update/%s/%s/%s", client.Domain, typeMetric, name, value)
-	response, err := client.HTTPClient.Post(postURL, "text/plain; charset=utf-8", nil)
+	req, err := http.NewRequest(http.MethodPost, postURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if client.RealIP != "" {
+		req.Header.Set(common.RealIPHeader, client.RealIP)
+	}
+
+	response, err := client.HTTPClient.Do(req)
 	if err != nil {
 		log.Printf("Failed to create resource at: %s and the error is: %v\n", postURL, err)
 		return err
@@ -48,19 +84,62 @@ func (client *Client) SendMetric(typeMetric string, name string, value string) e
 	return nil
 }
 
-func (client *Client) SendData(data *bytes.Buffer, hash string) error {
+// ErrBatchRejected сервер окончательно отклонил пачку: повторная отправка вернет ту же ошибку,
+// поэтому такая пачка не повторяется и не сохраняется в спул
+var ErrBatchRejected = errors.New("batch rejected by server")
+
+// sendRetryConfig повторяет отправку пачки, только если ошибка может быть временной
+var sendRetryConfig = retry.RetryConfig{
+	MaxRetries: retry.AgentRetryConfig.MaxRetries,
+	Delays:     retry.AgentRetryConfig.Delays,
+	ShouldRetry: func(err error) bool {
+		return !errors.Is(err, ErrBatchRejected)
+	},
+}
+
+// isRejectedStatus ошибки клиента, кроме таймаута и превышения лимита запросов, повтором не исправить
+func isRejectedStatus(statusCode int) bool {
+	return statusCode >= 400 && statusCode < 500 &&
+		statusCode != http.StatusRequestTimeout && statusCode != http.StatusTooManyRequests
+}
+
+func (client *Client) SendData(data *bytes.Buffer, hash string, batchID string) error {
 	postURL := fmt.Sprintf("%s/updates/", client.Domain)
+	payload := data.Bytes()
+
+	if client.PublicKey != nil {
+		encrypted, err := encryption.Encrypt(client.PublicKey, payload)
+		if err != nil {
+			return err
+		}
+		payload = encrypted
+	}
 
 	return retry.DoRetry(context.Background(), func() error {
-		req, err := http.NewRequest(http.MethodPost, postURL, data)
+		// тело запроса создается заново на каждую попытку, иначе повтор уйдет с пустым телом
+		req, err := http.NewRequest(http.MethodPost, postURL, bytes.NewReader(payload))
 		if err != nil {
 			logger.Log.Error("Error creating request", zap.Error(err))
+			return err
 		}
 		req.Header.Add("Content-Type", "application/json")
 		req.Header.Add("Content-Encoding", "gzip")
+		if client.PublicKey != nil {
+			req.Header.Add(encryption.Header, encryption.Scheme)
+		}
+		if client.RealIP != "" {
+			req.Header.Add(common.RealIPHeader, client.RealIP)
+		}
 
 		if hash != "" {
 			req.Header.Add(common.HashHeader, hash)
+			if client.KeyID != "" {
+				req.Header.Add(keyring.Header, client.KeyID)
+			}
+		}
+		// все попытки отправки несут один идентификатор, повтор не будет применен сервером дважды
+		if batchID != "" {
+			req.Header.Add(common.BatchIDHeader, batchID)
 		}
 
 		var response *http.Response
@@ -79,12 +158,15 @@ func (client *Client) SendData(data *bytes.Buffer, hash string) error {
 		if response.StatusCode != http.StatusOK {
 			body, _ := io.ReadAll(response.Body)
 			log.Printf("Server returned non-OK status: %d, body: %s\n", response.StatusCode, string(body))
+			if isRejectedStatus(response.StatusCode) {
+				return fmt.Errorf("%w: server returned status: %d", ErrBatchRejected, response.StatusCode)
+			}
 			return fmt.Errorf("server returned status: %d", response.StatusCode)
 		}
 
 		log.Print("Successful sending data")
 		return nil
-	}, retry.AgentRetryConfig)
+	}, sendRetryConfig)
 
 }
 
@@ -134,3 +216,18 @@ func CompressJSONMetrics(metrics []models.Metrics) (*bytes.Buffer, error) {
 
 	return &compressData, nil
 }
+
+// DecompressJSONMetrics разбирает пачку, сжатую CompressJSONMetrics
+func DecompressJSONMetrics(data []byte) ([]models.Metrics, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed init decompress reader: %v", err)
+	}
+	defer reader.Close()
+
+	var metrics []models.Metrics
+	if err = json.NewDecoder(reader).Decode(&metrics); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metrics: %v", err)
+	}
+	return metrics, nil
+}
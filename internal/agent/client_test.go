@@ -1,9 +1,21 @@
 package agent
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/Bessima/metrics-collect/internal/common"
+	"github.com/Bessima/metrics-collect/internal/keyring"
+	"github.com/Bessima/metrics-collect/internal/middlewares/decrypt"
+	"github.com/Bessima/metrics-collect/internal/middlewares/hash"
+	models "github.com/Bessima/metrics-collect/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSendMetric(t *testing.T) {
@@ -59,3 +71,91 @@ func TestSendMetric(t *testing.T) {
 		})
 	}
 }
+
+func TestDecompressJSONMetrics(t *testing.T) {
+	metrics := []models.Metrics{NewCounter("PollCount", 3), NewGauge("Alloc", 1.5)}
+
+	data, err := CompressJSONMetrics(metrics)
+	require.NoError(t, err)
+
+	decoded, err := DecompressJSONMetrics(data.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, metrics, decoded)
+
+	_, err = DecompressJSONMetrics([]byte("not gzip"))
+	assert.Error(t, err)
+}
+
+func TestClient_SendDataEncrypted(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var received []byte
+	server := httptest.NewServer(decrypt.DecryptMiddleware(key)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+	})))
+	defer server.Close()
+
+	data, err := CompressJSONMetrics([]models.Metrics{NewGauge("Alloc", 1.5)})
+	require.NoError(t, err)
+	payload := append([]byte(nil), data.Bytes()...)
+
+	client := Client{Domain: server.URL, HTTPClient: server.Client(), PublicKey: &key.PublicKey}
+	require.NoError(t, client.SendData(data, "", ""))
+
+	assert.Equal(t, payload, received)
+}
+
+func TestClient_SendDataRealIP(t *testing.T) {
+	var realIP string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		realIP = r.Header.Get(common.RealIPHeader)
+	}))
+	defer server.Close()
+
+	ip, err := OutboundIP(server.Listener.Addr().String())
+	require.NoError(t, err)
+	assert.True(t, ip.Equal(net.ParseIP("127.0.0.1")))
+
+	data, err := CompressJSONMetrics([]models.Metrics{NewGauge("Alloc", 1.5)})
+	require.NoError(t, err)
+
+	client := Client{Domain: server.URL, HTTPClient: server.Client(), RealIP: ip.String()}
+	require.NoError(t, client.SendData(data, "", ""))
+
+	assert.Equal(t, "127.0.0.1", realIP)
+}
+
+func TestClient_SendDataKeyID(t *testing.T) {
+	keys, err := keyring.New("", []keyring.Key{{ID: "agent-1", Secret: "agent-secret"}})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(hash.KeyRingCheckerMiddleware(keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	defer server.Close()
+
+	data, err := CompressJSONMetrics([]models.Metrics{NewGauge("Alloc", 1.5)})
+	require.NoError(t, err)
+	signature := common.GetHashData(data.Bytes(), "agent-secret")
+
+	client := Client{Domain: server.URL, HTTPClient: server.Client(), KeyID: "agent-1"}
+	require.NoError(t, client.SendData(data, signature, ""))
+}
+
+func TestClient_SendDataRejected(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	data, err := CompressJSONMetrics([]models.Metrics{NewGauge("Alloc", 1.5)})
+	require.NoError(t, err)
+
+	client := Client{Domain: server.URL, HTTPClient: server.Client()}
+	err = client.SendData(data, "", "")
+
+	// отклоненная пачка не повторяется
+	assert.ErrorIs(t, err, ErrBatchRejected)
+	assert.Equal(t, 1, requests)
+}
@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+)
+
+// Collector источник метрик агента.
+// Счетчики возвращаются приростом с момента предыдущего вызова Collect.
+type Collector interface {
+	Name() string
+	Interval() time.Duration
+	Collect(ctx context.Context) ([]models.Metrics, error)
+}
+
+// Listener коллектор, который принимает метрики в фоне, например из сети.
+// Start вызывается один раз до первого Collect, прием работает до отмены ctx.
+type Listener interface {
+	Start(ctx context.Context) error
+}
+
+// CollectorFactory создает коллектор с заданным интервалом опроса и собственными настройками
+type CollectorFactory func(interval time.Duration, options json.RawMessage) (Collector, error)
+
+// CollectorConfig настройки коллектора в конфигурации агента
+type CollectorConfig struct {
+	Enabled  *bool           `json:"enabled,omitempty"`
+	Interval Duration        `json:"interval,omitempty"`
+	Options  json.RawMessage `json:"options,omitempty"`
+}
+
+type registeredCollector struct {
+	factory          CollectorFactory
+	enabledByDefault bool
+}
+
+var (
+	collectorsMutex sync.RWMutex
+	collectors      = make(map[string]registeredCollector)
+)
+
+// RegisterCollector регистрирует коллектор под именем name.
+// Коллекторы с enabledByDefault запускаются, если не выключены в конфигурации.
+func RegisterCollector(name string, factory CollectorFactory, enabledByDefault bool) {
+	collectorsMutex.Lock()
+	defer collectorsMutex.Unlock()
+
+	if _, exists := collectors[name]; exists {
+		panic(fmt.Sprintf("collector %s is already registered", name))
+	}
+	collectors[name] = registeredCollector{factory: factory, enabledByDefault: enabledByDefault}
+}
+
+// RegisteredCollectors возвращает имена всех зарегистрированных коллекторов
+func RegisteredCollectors() []string {
+	collectorsMutex.RLock()
+	defer collectorsMutex.RUnlock()
+
+	return sortedCollectorNames()
+}
+
+// NewCollectors создает включенные коллекторы.
+// Если enabled не пуст, запускаются только перечисленные в нем коллекторы.
+// Коллекторы без своего интервала опрашиваются раз в defaultInterval.
+func NewCollectors(configs map[string]CollectorConfig, enabled []string, defaultInterval time.Duration) ([]Collector, error) {
+	for name := range configs {
+		if !isRegisteredCollector(name) {
+			return nil, fmt.Errorf("unknown collector %s", name)
+		}
+	}
+
+	enabledSet := make(map[string]bool, len(enabled))
+	for _, name := range enabled {
+		if !isRegisteredCollector(name) {
+			return nil, fmt.Errorf("unknown collector %s", name)
+		}
+		enabledSet[name] = true
+	}
+
+	collectorsMutex.RLock()
+	defer collectorsMutex.RUnlock()
+
+	result := make([]Collector, 0, len(collectors))
+	for _, name := range sortedCollectorNames() {
+		registered := collectors[name]
+		config := configs[name]
+
+		isEnabled := registered.enabledByDefault
+		if config.Enabled != nil {
+			isEnabled = *config.Enabled
+		}
+		if len(enabledSet) > 0 {
+			isEnabled = enabledSet[name]
+		}
+		if !isEnabled {
+			continue
+		}
+
+		interval := time.Duration(config.Interval)
+		if interval <= 0 {
+			interval = defaultInterval
+		}
+
+		collector, err := registered.factory(interval, config.Options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collector %s: %v", name, err)
+		}
+		result = append(result, collector)
+	}
+	return result, nil
+}
+
+// sortedCollectorNames возвращает отсортированные имена коллекторов, вызывается под collectorsMutex
+func sortedCollectorNames() []string {
+	names := make([]string, 0, len(collectors))
+	for name := range collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isRegisteredCollector(name string) bool {
+	collectorsMutex.RLock()
+	defer collectorsMutex.RUnlock()
+
+	_, exists := collectors[name]
+	return exists
+}
+
+// decodeOptions читает настройки коллектора, пустые настройки оставляют значения по умолчанию
+func decodeOptions(options json.RawMessage, target any) error {
+	if len(options) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(options, target); err != nil {
+		return fmt.Errorf("invalid options: %v", err)
+	}
+	return nil
+}
+
+// Duration интервал в конфигурации: строка в формате time.ParseDuration или число секунд
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch value := value.(type) {
+	case float64:
+		*d = Duration(value * float64(time.Second))
+	case string:
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+			*d = Duration(seconds * float64(time.Second))
+			return nil
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration %s", string(data))
+	}
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// NewGauge создает метрику типа gauge
+func NewGauge(name string, value float64) models.Metrics {
+	return models.Metrics{ID: name, MType: models.Gauge, Value: &value}
+}
+
+// NewCounter создает метрику типа counter
+func NewCounter(name string, delta int64) models.Metrics {
+	return models.Metrics{ID: name, MType: models.Counter, Delta: &delta}
+}
@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func collectorNames(collectors []Collector) []string {
+	names := make([]string, 0, len(collectors))
+	for _, collector := range collectors {
+		names = append(names, collector.Name())
+	}
+	return names
+}
+
+func TestNewCollectors_Defaults(t *testing.T) {
+	collectors, err := NewCollectors(nil, nil, 2*time.Second)
+
+	require.NoError(t, err)
+	names := collectorNames(collectors)
+	assert.Contains(t, names, MemStatsCollectorName)
+	assert.Contains(t, names, PollCollectorName)
+	assert.Contains(t, names, MemoryCollectorName)
+	for _, collector := range collectors {
+		assert.Equal(t, 2*time.Second, collector.Interval())
+	}
+}
+
+func TestNewCollectors_DisableAndInterval(t *testing.T) {
+	disabled := false
+	configs := map[string]CollectorConfig{
+		MemoryCollectorName: {Enabled: &disabled},
+		PollCollectorName:   {Interval: Duration(5 * time.Second)},
+	}
+
+	collectors, err := NewCollectors(configs, nil, 2*time.Second)
+
+	require.NoError(t, err)
+	assert.NotContains(t, collectorNames(collectors), MemoryCollectorName)
+	for _, collector := range collectors {
+		if collector.Name() == PollCollectorName {
+			assert.Equal(t, 5*time.Second, collector.Interval())
+		}
+	}
+}
+
+func TestNewCollectors_EnabledList(t *testing.T) {
+	collectors, err := NewCollectors(nil, []string{PollCollectorName}, time.Second)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{PollCollectorName}, collectorNames(collectors))
+}
+
+func TestNewCollectors_Unknown(t *testing.T) {
+	_, err := NewCollectors(nil, []string{"unknown"}, time.Second)
+	assert.Error(t, err)
+
+	_, err = NewCollectors(map[string]CollectorConfig{"unknown": {}}, nil, time.Second)
+	assert.Error(t, err)
+}
+
+func TestDuration_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{name: "seconds number", data: `10`, expected: 10 * time.Second},
+		{name: "duration string", data: `"1m30s"`, expected: 90 * time.Second},
+		{name: "seconds string", data: `"0.5"`, expected: 500 * time.Millisecond},
+		{name: "invalid", data: `"ten"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d Duration
+			err := json.Unmarshal([]byte(tt.data), &d)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, time.Duration(d))
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"sort"
+	"sync"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+)
+
+// CounterTracker накапливает значения счетчиков агента и отдает на отправку только
+// прирост с момента последней успешной отправки.
+// Прирост неудачной отправки не теряется и попадает в следующий отчет.
+type CounterTracker struct {
+	mutex    sync.Mutex
+	totals   map[string]int64
+	reported map[string]int64
+	inFlight map[string]int64
+}
+
+func NewCounterTracker() *CounterTracker {
+	return &CounterTracker{
+		totals:   make(map[string]int64),
+		reported: make(map[string]int64),
+		inFlight: make(map[string]int64),
+	}
+}
+
+// Add увеличивает накопленное значение счетчика
+func (tracker *CounterTracker) Add(name string, delta int64) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	tracker.totals[name] += delta
+}
+
+// Take возвращает прирост счетчиков, еще не отправленный на сервер,
+// и помечает его как находящийся в отправке
+func (tracker *CounterTracker) Take() []models.Metrics {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	names := make([]string, 0, len(tracker.totals))
+	for name := range tracker.totals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metrics := make([]models.Metrics, 0, len(names))
+	for _, name := range names {
+		delta := tracker.totals[name] - tracker.reported[name] - tracker.inFlight[name]
+		if delta == 0 {
+			continue
+		}
+		tracker.inFlight[name] += delta
+		metrics = append(metrics, models.Metrics{ID: name, MType: models.Counter, Delta: &delta})
+	}
+	return metrics
+}
+
+// Ack фиксирует прирост счетчиков из доставленной пачки
+func (tracker *CounterTracker) Ack(metrics []models.Metrics) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	for _, metric := range metrics {
+		if metric.MType != models.Counter || metric.Delta == nil {
+			continue
+		}
+		tracker.inFlight[metric.ID] -= *metric.Delta
+		tracker.reported[metric.ID] += *metric.Delta
+	}
+}
+
+// Fail возвращает прирост счетчиков из недоставленной пачки в следующий отчет
+func (tracker *CounterTracker) Fail(metrics []models.Metrics) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	for _, metric := range metrics {
+		if metric.MType != models.Counter || metric.Delta == nil {
+			continue
+		}
+		tracker.inFlight[metric.ID] -= *metric.Delta
+	}
+}
+
+// Reported возвращает последнее подтвержденное значение счетчика
+func (tracker *CounterTracker) Reported(name string) int64 {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	return tracker.reported[name]
+}
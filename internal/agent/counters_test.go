@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"testing"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func deltaOf(t *testing.T, metrics []models.Metrics, name string) int64 {
+	t.Helper()
+	for _, metric := range metrics {
+		if metric.ID == name {
+			require.NotNil(t, metric.Delta)
+			return *metric.Delta
+		}
+	}
+	t.Fatalf("counter %s not found", name)
+	return 0
+}
+
+func TestCounterTracker_SendsDeltaSinceLastAck(t *testing.T) {
+	tracker := NewCounterTracker()
+
+	for i := 0; i < 5; i++ {
+		tracker.Add(CounterPollCountMetric, 1)
+	}
+	first := tracker.Take()
+	assert.Equal(t, int64(5), deltaOf(t, first, CounterPollCountMetric))
+	tracker.Ack(first)
+
+	for i := 0; i < 3; i++ {
+		tracker.Add(CounterPollCountMetric, 1)
+	}
+	second := tracker.Take()
+	assert.Equal(t, int64(3), deltaOf(t, second, CounterPollCountMetric))
+	tracker.Ack(second)
+
+	assert.Equal(t, int64(8), tracker.Reported(CounterPollCountMetric))
+}
+
+func TestCounterTracker_FailedDeltaRollsIntoNextReport(t *testing.T) {
+	tracker := NewCounterTracker()
+
+	tracker.Add(CounterPollCountMetric, 4)
+	failed := tracker.Take()
+	tracker.Fail(failed)
+
+	tracker.Add(CounterPollCountMetric, 2)
+	next := tracker.Take()
+
+	assert.Equal(t, int64(6), deltaOf(t, next, CounterPollCountMetric))
+	tracker.Ack(next)
+	assert.Equal(t, int64(6), tracker.Reported(CounterPollCountMetric))
+}
+
+func TestCounterTracker_InFlightIsNotSentTwice(t *testing.T) {
+	tracker := NewCounterTracker()
+
+	tracker.Add(CounterPollCountMetric, 4)
+	inFlight := tracker.Take()
+
+	tracker.Add(CounterPollCountMetric, 1)
+	next := tracker.Take()
+	assert.Equal(t, int64(1), deltaOf(t, next, CounterPollCountMetric))
+
+	tracker.Ack(inFlight)
+	tracker.Ack(next)
+	assert.Empty(t, tracker.Take())
+	assert.Equal(t, int64(5), tracker.Reported(CounterPollCountMetric))
+}
+
+func TestCounterTracker_IgnoresGauges(t *testing.T) {
+	tracker := NewCounterTracker()
+	value := 1.5
+
+	tracker.Ack([]models.Metrics{{ID: "Alloc", MType: models.Gauge, Value: &value}})
+	tracker.Fail([]models.Metrics{{ID: "Alloc", MType: models.Gauge, Value: &value}})
+
+	assert.Empty(t, tracker.Take())
+}
@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/load"
+)
+
+const CPUCollectorName = "cpu"
+
+func init() {
+	RegisterCollector(CPUCollectorName, func(interval time.Duration, _ json.RawMessage) (Collector, error) {
+		return NewCPUCollector(interval), nil
+	}, true)
+}
+
+// CPUCollector собирает загрузку процессора по каждому логическому ядру и в целом.
+// Загрузка считается за время между двумя опросами, а не мгновенно.
+type CPUCollector struct {
+	interval time.Duration
+
+	mutex     sync.Mutex
+	lastTimes *cpu.TimesStat
+
+	percent func(ctx context.Context, interval time.Duration, percpu bool) ([]float64, error)
+	times   func(ctx context.Context, percpu bool) ([]cpu.TimesStat, error)
+	loadAvg func(ctx context.Context) (*load.AvgStat, error)
+}
+
+func NewCPUCollector(interval time.Duration) *CPUCollector {
+	return &CPUCollector{
+		interval: interval,
+		percent:  cpu.PercentWithContext,
+		times:    cpu.TimesWithContext,
+		loadAvg:  load.AvgWithContext,
+	}
+}
+
+func (c *CPUCollector) Name() string {
+	return CPUCollectorName
+}
+
+func (c *CPUCollector) Interval() time.Duration {
+	return c.interval
+}
+
+func (c *CPUCollector) Collect(ctx context.Context) ([]models.Metrics, error) {
+	metrics := make([]models.Metrics, 0)
+	errs := make([]error, 0)
+
+	// при нулевом интервале gopsutil сравнивает с предыдущим вызовом, то есть с прошлым опросом
+	perCore, err := c.percent(ctx, 0, true)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error getting cpu percent: %v", err))
+	}
+	for i, value := range perCore {
+		metrics = append(metrics, NewGauge(fmt.Sprintf("CPUutilization%d", i+1), value))
+	}
+
+	breakdown, err := c.collectBreakdown(ctx)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	metrics = append(metrics, breakdown...)
+
+	avg, err := c.loadAvg(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error getting load average: %v", err))
+	} else if avg != nil {
+		metrics = append(metrics,
+			NewGauge("LoadAverage1", avg.Load1),
+			NewGauge("LoadAverage5", avg.Load5),
+			NewGauge("LoadAverage15", avg.Load15),
+		)
+	}
+
+	return metrics, errors.Join(errs...)
+}
+
+// collectBreakdown считает доли user/system/iowait/steal с момента прошлого опроса
+func (c *CPUCollector) collectBreakdown(ctx context.Context) ([]models.Metrics, error) {
+	times, err := c.times(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("error getting cpu times: %v", err)
+	}
+	if len(times) == 0 {
+		return nil, nil
+	}
+	current := times[0]
+
+	c.mutex.Lock()
+	last := c.lastTimes
+	c.lastTimes = &current
+	c.mutex.Unlock()
+
+	if last == nil {
+		return nil, nil
+	}
+
+	total := current.Total() - last.Total()
+	if total <= 0 {
+		return nil, nil
+	}
+	share := func(now, before float64) float64 {
+		return clampPercent((now - before) / total * 100)
+	}
+
+	return []models.Metrics{
+		NewGauge("CPUUser", share(current.User, last.User)),
+		NewGauge("CPUSystem", share(current.System, last.System)),
+		NewGauge("CPUIowait", share(current.Iowait, last.Iowait)),
+		NewGauge("CPUSteal", share(current.Steal, last.Steal)),
+	}, nil
+}
+
+func clampPercent(value float64) float64 {
+	if value < 0 {
+		return 0
+	}
+	if value > 100 {
+		return 100
+	}
+	return value
+}
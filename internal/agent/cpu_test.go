@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/load"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gaugeValues(metrics []models.Metrics) map[string]float64 {
+	values := make(map[string]float64, len(metrics))
+	for _, metric := range metrics {
+		if metric.MType == models.Gauge && metric.Value != nil {
+			values[metric.ID] = *metric.Value
+		}
+	}
+	return values
+}
+
+func TestCPUCollector_Collect(t *testing.T) {
+	samples := [][]cpu.TimesStat{
+		{{CPU: "cpu-total", User: 100, System: 50, Idle: 800, Iowait: 40, Steal: 10}},
+		{{CPU: "cpu-total", User: 150, System: 60, Idle: 820, Iowait: 50, Steal: 20}},
+	}
+	call := 0
+
+	collector := NewCPUCollector(time.Second)
+	collector.percent = func(_ context.Context, _ time.Duration, _ bool) ([]float64, error) {
+		return []float64{12.5, 50}, nil
+	}
+	collector.times = func(_ context.Context, _ bool) ([]cpu.TimesStat, error) {
+		sample := samples[call]
+		call++
+		return sample, nil
+	}
+	collector.loadAvg = func(_ context.Context) (*load.AvgStat, error) {
+		return &load.AvgStat{Load1: 1, Load5: 0.5, Load15: 0.25}, nil
+	}
+
+	first, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+	values := gaugeValues(first)
+	assert.Equal(t, 12.5, values["CPUutilization1"])
+	assert.Equal(t, 50.0, values["CPUutilization2"])
+	assert.Equal(t, 1.0, values["LoadAverage1"])
+	// первый опрос только запоминает отсчет
+	assert.NotContains(t, values, "CPUUser")
+
+	second, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+	values = gaugeValues(second)
+	// всего прошло 100 единиц времени
+	assert.InDelta(t, 50.0, values["CPUUser"], 0.001)
+	assert.InDelta(t, 10.0, values["CPUSystem"], 0.001)
+	assert.InDelta(t, 10.0, values["CPUIowait"], 0.001)
+	assert.InDelta(t, 10.0, values["CPUSteal"], 0.001)
+}
+
+func TestCPUCollector_RealSystem(t *testing.T) {
+	collector := NewCPUCollector(time.Second)
+
+	_, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+
+	metrics, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, gaugeValues(metrics), "CPUutilization1")
+}
@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+const DiskCollectorName = "disk"
+
+// DiskOptions настройки коллектора дисков
+type DiskOptions struct {
+	// Mountpoints шаблоны точек монтирования, например "/" или "/var/*"
+	Mountpoints PatternFilter `json:"mountpoints"`
+	// Fstypes шаблоны типов файловых систем
+	Fstypes PatternFilter `json:"fstypes"`
+	// Devices шаблоны имен устройств для счетчиков ввода-вывода
+	Devices PatternFilter `json:"devices"`
+}
+
+func defaultDiskOptions() DiskOptions {
+	return DiskOptions{
+		Fstypes: PatternFilter{Exclude: []string{"tmpfs", "devtmpfs", "overlay", "squashfs", "proc", "sysfs", "cgroup*"}},
+		Devices: PatternFilter{Exclude: []string{"loop*", "ram*"}},
+	}
+}
+
+func init() {
+	RegisterCollector(DiskCollectorName, func(interval time.Duration, options json.RawMessage) (Collector, error) {
+		diskOptions := defaultDiskOptions()
+		if err := decodeOptions(options, &diskOptions); err != nil {
+			return nil, err
+		}
+		return NewDiskCollector(interval, diskOptions), nil
+	}, false)
+}
+
+// DiskCollector собирает заполненность файловых систем и счетчики ввода-вывода устройств
+type DiskCollector struct {
+	interval time.Duration
+	options  DiskOptions
+	counters *cumulativeCounters
+
+	partitions func(ctx context.Context, all bool) ([]disk.PartitionStat, error)
+	usage      func(ctx context.Context, path string) (*disk.UsageStat, error)
+	ioCounters func(ctx context.Context, names ...string) (map[string]disk.IOCountersStat, error)
+}
+
+func NewDiskCollector(interval time.Duration, options DiskOptions) *DiskCollector {
+	return &DiskCollector{
+		interval:   interval,
+		options:    options,
+		counters:   newCumulativeCounters(),
+		partitions: disk.PartitionsWithContext,
+		usage:      disk.UsageWithContext,
+		ioCounters: disk.IOCountersWithContext,
+	}
+}
+
+func (c *DiskCollector) Name() string {
+	return DiskCollectorName
+}
+
+func (c *DiskCollector) Interval() time.Duration {
+	return c.interval
+}
+
+func (c *DiskCollector) Collect(ctx context.Context) ([]models.Metrics, error) {
+	metrics, usageErr := c.collectUsage(ctx)
+	ioMetrics, ioErr := c.collectIO(ctx)
+
+	return append(metrics, ioMetrics...), errors.Join(usageErr, ioErr)
+}
+
+func (c *DiskCollector) collectUsage(ctx context.Context) ([]models.Metrics, error) {
+	partitions, err := c.partitions(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("error getting partitions: %v", err)
+	}
+
+	metrics := make([]models.Metrics, 0)
+	errs := make([]error, 0)
+	seen := make(map[string]bool, len(partitions))
+
+	for _, partition := range partitions {
+		if seen[partition.Mountpoint] {
+			continue
+		}
+		if !c.options.Mountpoints.Allowed(partition.Mountpoint) || !c.options.Fstypes.Allowed(partition.Fstype) {
+			continue
+		}
+		seen[partition.Mountpoint] = true
+
+		usage, err := c.usage(ctx, partition.Mountpoint)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error getting usage of %s: %v", partition.Mountpoint, err))
+			continue
+		}
+
+		metrics = append(metrics,
+			NewGauge(labeledName("DiskUsed", partition.Mountpoint), float64(usage.Used)),
+			NewGauge(labeledName("DiskFree", partition.Mountpoint), float64(usage.Free)),
+			NewGauge(labeledName("DiskUsedPercent", partition.Mountpoint), usage.UsedPercent),
+			NewGauge(labeledName("DiskInodesUsed", partition.Mountpoint), float64(usage.InodesUsed)),
+			NewGauge(labeledName("DiskInodesFree", partition.Mountpoint), float64(usage.InodesFree)),
+		)
+	}
+	return metrics, errors.Join(errs...)
+}
+
+func (c *DiskCollector) collectIO(ctx context.Context) ([]models.Metrics, error) {
+	stats, err := c.ioCounters(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting disk io counters: %v", err)
+	}
+
+	devices := make([]string, 0, len(stats))
+	for device := range stats {
+		if c.options.Devices.Allowed(device) {
+			devices = append(devices, device)
+		}
+	}
+	sort.Strings(devices)
+
+	metrics := make([]models.Metrics, 0, len(devices)*4)
+	for _, device := range devices {
+		stat := stats[device]
+		values := []struct {
+			name  string
+			value uint64
+		}{
+			{labeledName("DiskReadBytes", device), stat.ReadBytes},
+			{labeledName("DiskWriteBytes", device), stat.WriteBytes},
+			{labeledName("DiskReadOps", device), stat.ReadCount},
+			{labeledName("DiskWriteOps", device), stat.WriteCount},
+		}
+		for _, item := range values {
+			if delta, ok := c.counters.delta(item.name, item.value); ok {
+				metrics = append(metrics, NewCounter(item.name, delta))
+			}
+		}
+	}
+	return metrics, nil
+}
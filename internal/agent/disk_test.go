@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func counterValues(metrics []models.Metrics) map[string]int64 {
+	values := make(map[string]int64, len(metrics))
+	for _, metric := range metrics {
+		if metric.MType == models.Counter && metric.Delta != nil {
+			values[metric.ID] = *metric.Delta
+		}
+	}
+	return values
+}
+
+func newFakeDiskCollector(options DiskOptions, io []map[string]disk.IOCountersStat) *DiskCollector {
+	call := 0
+	collector := NewDiskCollector(time.Second, options)
+	collector.partitions = func(_ context.Context, _ bool) ([]disk.PartitionStat, error) {
+		return []disk.PartitionStat{
+			{Device: "/dev/sda1", Mountpoint: "/", Fstype: "ext4"},
+			{Device: "/dev/sda2", Mountpoint: "/var/lib", Fstype: "xfs"},
+			{Device: "tmpfs", Mountpoint: "/run", Fstype: "tmpfs"},
+			{Device: "overlay", Mountpoint: "/var/lib/docker/overlay2/merged", Fstype: "overlay"},
+		}, nil
+	}
+	collector.usage = func(_ context.Context, path string) (*disk.UsageStat, error) {
+		return &disk.UsageStat{Path: path, Used: 100, Free: 300, UsedPercent: 25, InodesUsed: 10, InodesFree: 90}, nil
+	}
+	collector.ioCounters = func(_ context.Context, _ ...string) (map[string]disk.IOCountersStat, error) {
+		stats := io[call]
+		call++
+		return stats, nil
+	}
+	return collector
+}
+
+func TestDiskCollector_Collect(t *testing.T) {
+	io := []map[string]disk.IOCountersStat{
+		{
+			"sda":   {Name: "sda", ReadBytes: 1000, WriteBytes: 2000, ReadCount: 10, WriteCount: 20},
+			"loop0": {Name: "loop0", ReadBytes: 1},
+		},
+		{
+			"sda":   {Name: "sda", ReadBytes: 1500, WriteBytes: 2100, ReadCount: 15, WriteCount: 21},
+			"loop0": {Name: "loop0", ReadBytes: 2},
+		},
+	}
+	collector := newFakeDiskCollector(defaultDiskOptions(), io)
+
+	first, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+
+	gauges := gaugeValues(first)
+	assert.Equal(t, 100.0, gauges["DiskUsed__root"])
+	assert.Equal(t, 300.0, gauges["DiskFree_var_lib"])
+	assert.Equal(t, 90.0, gauges["DiskInodesFree__root"])
+	assert.NotContains(t, gauges, "DiskUsed_run")
+	assert.NotContains(t, gauges, "DiskUsed_var_lib_docker_overlay2_merged")
+	// первый опрос только запоминает значения счетчиков
+	assert.Empty(t, counterValues(first))
+
+	second, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+
+	counters := counterValues(second)
+	assert.Equal(t, int64(500), counters["DiskReadBytes_sda"])
+	assert.Equal(t, int64(100), counters["DiskWriteBytes_sda"])
+	assert.Equal(t, int64(5), counters["DiskReadOps_sda"])
+	assert.Equal(t, int64(1), counters["DiskWriteOps_sda"])
+	assert.NotContains(t, counters, "DiskReadBytes_loop0")
+}
+
+func TestDiskCollector_MountpointInclude(t *testing.T) {
+	var options DiskOptions
+	require.NoError(t, json.Unmarshal([]byte(`{"mountpoints": {"include": ["/var/*"]}}`), &options))
+
+	io := []map[string]disk.IOCountersStat{{}}
+	collector := newFakeDiskCollector(options, io)
+
+	metrics, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+
+	gauges := gaugeValues(metrics)
+	assert.Contains(t, gauges, "DiskUsed_var_lib")
+	assert.NotContains(t, gauges, "DiskUsed__root")
+}
@@ -0,0 +1,264 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+)
+
+const ExecCollectorName = "exec"
+
+const (
+	defaultExecTimeout = 10 * time.Second
+	// execWaitDelay время на закрытие вывода после завершения команды, если его держат дочерние процессы
+	execWaitDelay = time.Second
+)
+
+const (
+	ExecFormatAuto  = ""
+	ExecFormatLines = "lines"
+	ExecFormatJSON  = "json"
+)
+
+// ExecCommand внешняя команда, вывод которой превращается в метрики
+type ExecCommand struct {
+	// Name имя команды в метриках ExecFailures, ExecTimeouts и ExecParseErrors
+	Name string `json:"name"`
+	// Command программа и ее аргументы, для shell используйте ["sh", "-c", "..."]
+	Command []string `json:"command"`
+	// Interval период запуска, по умолчанию интервал коллектора
+	Interval Duration `json:"interval,omitempty"`
+	// Timeout ограничение времени выполнения
+	Timeout Duration `json:"timeout,omitempty"`
+	// Format формат вывода: "lines", "json" или пустая строка для определения по выводу
+	Format string `json:"format,omitempty"`
+}
+
+// ExecOptions настройки коллектора внешних команд
+type ExecOptions struct {
+	Commands []ExecCommand `json:"commands"`
+}
+
+func init() {
+	RegisterCollector(ExecCollectorName, func(interval time.Duration, options json.RawMessage) (Collector, error) {
+		execOptions := ExecOptions{}
+		if err := decodeOptions(options, &execOptions); err != nil {
+			return nil, err
+		}
+		return NewExecCollector(interval, execOptions)
+	}, false)
+}
+
+// ExecCollector запускает внешние команды, каждую в своей горутине со своим интервалом и таймаутом,
+// поэтому медленная команда не задерживает остальные. Collect забирает накопленные результаты, не дожидаясь команд.
+// Вывод команды разбирается как строки "name type value" или JSON-массив метрик.
+// Ненулевой код завершения, таймаут и ошибка разбора вывода отправляются счетчиками.
+type ExecCollector struct {
+	interval time.Duration
+	commands []ExecCommand
+
+	mutex   sync.Mutex
+	metrics []models.Metrics
+	errs    []error
+}
+
+func NewExecCollector(interval time.Duration, options ExecOptions) (*ExecCollector, error) {
+	commands := make([]ExecCommand, 0, len(options.Commands))
+	names := make(map[string]bool, len(options.Commands))
+	minInterval := time.Duration(0)
+
+	for _, command := range options.Commands {
+		if command.Name == "" {
+			return nil, errors.New("exec command name is required")
+		}
+		if names[command.Name] {
+			return nil, fmt.Errorf("duplicate exec command %s", command.Name)
+		}
+		names[command.Name] = true
+
+		if len(command.Command) == 0 {
+			return nil, fmt.Errorf("exec command %s is empty", command.Name)
+		}
+		if command.Format != ExecFormatAuto && command.Format != ExecFormatLines && command.Format != ExecFormatJSON {
+			return nil, fmt.Errorf("unknown output format %q of exec command %s", command.Format, command.Name)
+		}
+		if command.Interval <= 0 {
+			command.Interval = Duration(interval)
+		}
+		if command.Timeout <= 0 {
+			command.Timeout = Duration(defaultExecTimeout)
+		}
+
+		if minInterval == 0 || time.Duration(command.Interval) < minInterval {
+			minInterval = time.Duration(command.Interval)
+		}
+		commands = append(commands, command)
+	}
+
+	// результаты забираются с интервалом самой частой команды
+	if minInterval > 0 {
+		interval = minInterval
+	}
+
+	return &ExecCollector{
+		interval: interval,
+		commands: commands,
+	}, nil
+}
+
+func (c *ExecCollector) Name() string {
+	return ExecCollectorName
+}
+
+func (c *ExecCollector) Interval() time.Duration {
+	return c.interval
+}
+
+// Start запускает каждую команду сразу и затем с ее интервалом до отмены ctx.
+// Пока команда выполняется, ее следующий запуск пропускается.
+func (c *ExecCollector) Start(ctx context.Context) error {
+	for _, command := range c.commands {
+		go c.schedule(ctx, command)
+	}
+	return nil
+}
+
+func (c *ExecCollector) schedule(ctx context.Context, command ExecCommand) {
+	ticker := time.NewTicker(time.Duration(command.Interval))
+	defer ticker.Stop()
+
+	for {
+		metrics, err := c.run(ctx, command)
+		if ctx.Err() != nil {
+			return
+		}
+		c.store(metrics, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *ExecCollector) store(metrics []models.Metrics, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.metrics = append(c.metrics, metrics...)
+	if err != nil {
+		c.errs = append(c.errs, err)
+	}
+}
+
+// Collect возвращает результаты команд, завершившихся с прошлого вызова
+func (c *ExecCollector) Collect(_ context.Context) ([]models.Metrics, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	metrics, errs := c.metrics, c.errs
+	c.metrics, c.errs = nil, nil
+	if metrics == nil {
+		metrics = make([]models.Metrics, 0)
+	}
+	return metrics, errors.Join(errs...)
+}
+
+func (c *ExecCollector) run(ctx context.Context, command ExecCommand) ([]models.Metrics, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(command.Timeout))
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command.Command[0], command.Command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = execWaitDelay
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return []models.Metrics{NewCounter(labeledName("ExecTimeouts", command.Name), 1)},
+			fmt.Errorf("exec %s: timeout after %s", command.Name, time.Duration(command.Timeout))
+	}
+	if err != nil {
+		return []models.Metrics{NewCounter(labeledName("ExecFailures", command.Name), 1)},
+			fmt.Errorf("exec %s: %v: %s", command.Name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	metrics, err := parseExecOutput(stdout.Bytes(), command.Format)
+	if err != nil {
+		return []models.Metrics{NewCounter(labeledName("ExecParseErrors", command.Name), 1)},
+			fmt.Errorf("exec %s: error parsing output: %v", command.Name, err)
+	}
+	return metrics, nil
+}
+
+func parseExecOutput(output []byte, format string) ([]models.Metrics, error) {
+	if format == ExecFormatAuto {
+		format = ExecFormatLines
+		if bytes.HasPrefix(bytes.TrimSpace(output), []byte("[")) {
+			format = ExecFormatJSON
+		}
+	}
+
+	if format == ExecFormatJSON {
+		var metrics []models.Metrics
+		if err := json.Unmarshal(output, &metrics); err != nil {
+			return nil, err
+		}
+		for _, metric := range metrics {
+			if err := validateMetric(metric); err != nil {
+				return nil, err
+			}
+		}
+		return metrics, nil
+	}
+	return parseExecLines(output)
+}
+
+// parseExecLines разбирает строки "name type value", пустые строки и строки с "#" пропускаются
+func parseExecLines(output []byte) ([]models.Metrics, error) {
+	metrics := make([]models.Metrics, 0)
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid line %q, expected \"name type value\"", line)
+		}
+
+		name, metricType, value := fields[0], fields[1], fields[2]
+		switch metricType {
+		case models.Counter:
+			delta, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid counter value in line %q", line)
+			}
+			metrics = append(metrics, NewCounter(name, delta))
+		case models.Gauge:
+			gauge, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid gauge value in line %q", line)
+			}
+			metrics = append(metrics, NewGauge(name, gauge))
+		default:
+			return nil, fmt.Errorf("unknown metric type in line %q", line)
+		}
+	}
+	return metrics, scanner.Err()
+}
@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExecOutput(t *testing.T) {
+	metrics, err := parseExecOutput([]byte("# проверка очереди\nqueue_size gauge 12.5\n\njobs_done counter 3\n"), ExecFormatAuto)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"queue_size": 12.5}, gaugeValues(metrics))
+	assert.Equal(t, map[string]int64{"jobs_done": 3}, counterValues(metrics))
+
+	metrics, err = parseExecOutput([]byte(`[{"id":"queue_size","type":"gauge","value":7}]`), ExecFormatAuto)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"queue_size": 7}, gaugeValues(metrics))
+
+	invalid := []struct {
+		output string
+		format string
+	}{
+		{output: "queue_size 12", format: ExecFormatLines},
+		{output: "queue_size histogram 12", format: ExecFormatLines},
+		{output: "jobs_done counter 1.5", format: ExecFormatLines},
+		{output: "queue_size gauge 1", format: ExecFormatJSON},
+		{output: `[{"id":"jobs_done","type":"counter"}]`, format: ExecFormatAuto},
+	}
+	for _, tt := range invalid {
+		_, err := parseExecOutput([]byte(tt.output), tt.format)
+		assert.Error(t, err, tt.output)
+	}
+}
+
+// collectExec собирает результаты коллектора, пока они не удовлетворят done
+func collectExec(t *testing.T, collector *ExecCollector, done func(counters map[string]int64) bool) (map[string]float64, map[string]int64, []error) {
+	t.Helper()
+
+	gauges, counters := map[string]float64{}, map[string]int64{}
+	errs := make([]error, 0)
+	require.Eventually(t, func() bool {
+		metrics, err := collector.Collect(context.Background())
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for name, value := range gaugeValues(metrics) {
+			gauges[name] = value
+		}
+		for name, delta := range counterValues(metrics) {
+			counters[name] += delta
+		}
+		return done(counters)
+	}, 3*time.Second, 10*time.Millisecond)
+	return gauges, counters, errs
+}
+
+func TestExecCollector_Collect(t *testing.T) {
+	collector, err := NewExecCollector(time.Hour, ExecOptions{Commands: []ExecCommand{
+		{Name: "ok", Command: []string{"sh", "-c", "echo 'queue_size gauge 5'; echo 'jobs_done counter 2'"}},
+		{Name: "failing", Command: []string{"sh", "-c", "echo broken >&2; exit 3"}},
+		{Name: "slow", Command: []string{"sleep", "5"}, Timeout: Duration(50 * time.Millisecond)},
+		{Name: "garbage", Command: []string{"echo", "not metrics"}},
+	}})
+	require.NoError(t, err)
+
+	// до запуска команд собирать нечего
+	metrics, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, metrics)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, collector.Start(ctx))
+
+	want := map[string]int64{
+		"jobs_done":               2,
+		"ExecFailures_failing":    1,
+		"ExecTimeouts_slow":       1,
+		"ExecParseErrors_garbage": 1,
+	}
+	gauges, counters, errs := collectExec(t, collector, func(counters map[string]int64) bool {
+		return len(counters) == len(want)
+	})
+
+	assert.NotEmpty(t, errs)
+	assert.Equal(t, map[string]float64{"queue_size": 5}, gauges)
+	assert.Equal(t, want, counters)
+}
+
+func TestExecCollector_OwnIntervals(t *testing.T) {
+	collector, err := NewExecCollector(time.Minute, ExecOptions{Commands: []ExecCommand{
+		{Name: "fast", Command: []string{"echo", "fast counter 1"}, Interval: Duration(20 * time.Millisecond)},
+		{Name: "rare", Command: []string{"echo", "rare counter 1"}, Interval: Duration(time.Hour)},
+		// медленная команда не задерживает остальные
+		{Name: "slow", Command: []string{"sh", "-c", "sleep 5; echo 'slow counter 1'"}, Interval: Duration(20 * time.Millisecond)},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, 20*time.Millisecond, collector.Interval())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, collector.Start(ctx))
+
+	_, counters, _ := collectExec(t, collector, func(counters map[string]int64) bool {
+		return counters["fast"] >= 5
+	})
+
+	assert.Equal(t, int64(1), counters["rare"])
+	assert.Zero(t, counters["slow"])
+}
+
+func TestNewExecCollector_InvalidOptions(t *testing.T) {
+	invalid := []ExecCommand{
+		{Command: []string{"true"}},
+		{Name: "empty"},
+		{Name: "format", Command: []string{"true"}, Format: "xml"},
+	}
+	for _, command := range invalid {
+		_, err := NewExecCollector(time.Second, ExecOptions{Commands: []ExecCommand{command}})
+		assert.Error(t, err)
+	}
+}
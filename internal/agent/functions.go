@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+)
+
+// labeledName добавляет к имени метрики метку, например точку монтирования или имя устройства.
+// Символы, недопустимые в имени метрики, заменяются на "_", крайние "_" отбрасываются.
+// Корень "/" превращается в "_root": после обрезки "_" такую метку не даст ни один путь, например "/root".
+func labeledName(base string, label string) string {
+	if label == "/" {
+		return base + "__root"
+	}
+
+	var builder strings.Builder
+	builder.Grow(len(label))
+	for _, r := range label {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			builder.WriteRune(r)
+		default:
+			builder.WriteByte('_')
+		}
+	}
+
+	sanitized := strings.Trim(builder.String(), "_")
+	if sanitized == "" {
+		return base
+	}
+	return base + "_" + sanitized
+}
+
+// sanitizeMetricName заменяет на "_" символы, недопустимые в имени метрики
+func sanitizeMetricName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// validateMetric проверяет метрику, полученную извне: имя, тип и значение, соответствующее типу
+func validateMetric(metric models.Metrics) error {
+	if metric.ID == "" {
+		return errors.New("metric id is required")
+	}
+	switch metric.MType {
+	case models.Counter:
+		if metric.Delta == nil {
+			return fmt.Errorf("counter %s has no delta", metric.ID)
+		}
+	case models.Gauge:
+		if metric.Value == nil {
+			return fmt.Errorf("gauge %s has no value", metric.ID)
+		}
+	default:
+		return fmt.Errorf("unknown type %q of metric %s", metric.MType, metric.ID)
+	}
+	return nil
+}
+
+// PatternFilter отбирает значения по шаблонам path.Match.
+// Пустой Include пропускает все значения, Exclude проверяется после Include.
+type PatternFilter struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+func (filter PatternFilter) Allowed(value string) bool {
+	if len(filter.Include) > 0 && !matchAny(filter.Include, value) {
+		return false
+	}
+	return !matchAny(filter.Exclude, value)
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, value); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// cumulativeCounters превращает накопительные значения системных счетчиков в приросты между опросами
+type cumulativeCounters struct {
+	mutex sync.Mutex
+	prev  map[string]uint64
+}
+
+func newCumulativeCounters() *cumulativeCounters {
+	return &cumulativeCounters{prev: make(map[string]uint64)}
+}
+
+// delta возвращает прирост счетчика с прошлого опроса.
+// Первое значение только запоминается, при сбросе счетчика приростом считается новое значение.
+func (counters *cumulativeCounters) delta(name string, value uint64) (int64, bool) {
+	counters.mutex.Lock()
+	defer counters.mutex.Unlock()
+
+	prev, exists := counters.prev[name]
+	counters.prev[name] = value
+	if !exists {
+		return 0, false
+	}
+	if value < prev {
+		return int64(value), true
+	}
+	return int64(value - prev), true
+}
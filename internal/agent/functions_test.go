@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabeledName(t *testing.T) {
+	tests := []struct {
+		base     string
+		label    string
+		expected string
+	}{
+		{base: "DiskUsed", label: "/", expected: "DiskUsed__root"},
+		{base: "DiskUsed", label: "/root", expected: "DiskUsed_root"},
+		{base: "DiskUsed", label: "/var/lib", expected: "DiskUsed_var_lib"},
+		{base: "NetBytesSent", label: "eth0", expected: "NetBytesSent_eth0"},
+		{base: "DiskUsed", label: "C:\\", expected: "DiskUsed_C"},
+		{base: "DiskUsed", label: "", expected: "DiskUsed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, labeledName(tt.base, tt.label))
+		})
+	}
+}
+
+func TestPatternFilter_Allowed(t *testing.T) {
+	filter := PatternFilter{Include: []string{"eth*", "veth*"}, Exclude: []string{"veth*"}}
+
+	assert.True(t, filter.Allowed("eth0"))
+	assert.False(t, filter.Allowed("veth12ab"))
+	assert.False(t, filter.Allowed("lo"))
+	assert.True(t, PatternFilter{}.Allowed("anything"))
+}
+
+func TestCumulativeCounters_Delta(t *testing.T) {
+	counters := newCumulativeCounters()
+
+	_, ok := counters.delta("bytes", 100)
+	assert.False(t, ok)
+
+	delta, ok := counters.delta("bytes", 150)
+	assert.True(t, ok)
+	assert.Equal(t, int64(50), delta)
+
+	// сброс счетчика, например после перезагрузки устройства
+	delta, ok = counters.delta("bytes", 20)
+	assert.True(t, ok)
+	assert.Equal(t, int64(20), delta)
+}
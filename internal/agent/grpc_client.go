@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Bessima/metrics-collect/internal/common"
+	"github.com/Bessima/metrics-collect/internal/interceptors"
+	"github.com/Bessima/metrics-collect/internal/metricspb"
+	models "github.com/Bessima/metrics-collect/internal/model"
+	"github.com/Bessima/metrics-collect/internal/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCClient отправляет пачки метрик потоком UpdateMetrics вместо JSON-запроса /updates/.
+// Метрики подписываются ключом агента перехватчиком interceptors.HashStreamClientInterceptor.
+type GRPCClient struct {
+	conn   *grpc.ClientConn
+	client metricspb.MetricsClient
+	// RealIP адрес агента для метаданных x-real-ip
+	RealIP string
+}
+
+// NewGRPCClient создает клиент, с tlsConfig — подключающийся по TLS.
+// keyID передается серверу, чтобы он выбрал ключ для проверки подписи.
+func NewGRPCClient(address string, key string, keyID string, tlsConfig *tls.Config) (*GRPCClient, error) {
+	transportCredentials := insecure.NewCredentials()
+	if tlsConfig != nil {
+		transportCredentials = credentials.NewTLS(tlsConfig)
+	}
+
+	conn, err := grpc.NewClient(address,
+		grpc.WithTransportCredentials(transportCredentials),
+		grpc.WithStreamInterceptor(interceptors.HashStreamClientInterceptor(key, keyID)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client: %v", err)
+	}
+	return &GRPCClient{conn: conn, client: metricspb.NewMetricsClient(conn)}, nil
+}
+
+// SendData отправляет пачку в том же виде, в котором она хранится в спуле: сжатый JSON
+func (client *GRPCClient) SendData(data []byte, batchID string) error {
+	metrics, err := DecompressJSONMetrics(data)
+	if err != nil {
+		// поврежденную пачку повторная отправка не исправит
+		return fmt.Errorf("%w: %v", ErrBatchRejected, err)
+	}
+
+	return retry.DoRetry(context.Background(), func() error {
+		return client.SendMetrics(context.Background(), batchID, metrics)
+	}, sendRetryConfig)
+}
+
+// SendMetrics отправляет пачку одним потоком, все попытки несут один идентификатор пачки
+func (client *GRPCClient) SendMetrics(ctx context.Context, batchID string, metrics []models.Metrics) error {
+	if batchID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, common.BatchIDHeader, batchID)
+	}
+	if client.RealIP != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, common.RealIPHeader, client.RealIP)
+	}
+
+	stream, err := client.client.UpdateMetrics(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open stream: %v", err)
+	}
+
+	for _, metric := range metrics {
+		// при io.EOF сервер уже закрыл поток, причину вернет CloseAndRecv
+		if err = stream.Send(metricspb.FromModel(metric)); errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to send metric %s: %v", metric.ID, err)
+		}
+	}
+
+	if _, err = stream.CloseAndRecv(); err != nil {
+		if isRejectedCode(status.Code(err)) {
+			return fmt.Errorf("%w: %v", ErrBatchRejected, err)
+		}
+		return fmt.Errorf("server rejected metrics: %v", err)
+	}
+	return nil
+}
+
+// isRejectedCode коды, с которыми сервер отклоняет саму пачку, а не временно недоступен
+func isRejectedCode(code codes.Code) bool {
+	switch code {
+	case codes.InvalidArgument, codes.Unauthenticated, codes.PermissionDenied, codes.FailedPrecondition,
+		codes.NotFound, codes.AlreadyExists, codes.OutOfRange, codes.Unimplemented:
+		return true
+	default:
+		return false
+	}
+}
+
+func (client *GRPCClient) Close() error {
+	return client.conn.Close()
+}
@@ -0,0 +1,360 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+)
+
+const LogTailCollectorName = "logtail"
+
+const (
+	defaultLogGroup  = "value"
+	logReadChunkSize = 64 * 1024
+	// maxLogLineSize строка длиннее разбирается по частям, чтобы не держать в памяти файл без переводов строки
+	maxLogLineSize = 1024 * 1024
+)
+
+// LogRule правило разбора строк журнала
+type LogRule struct {
+	// Name имя метрики
+	Name string `json:"name"`
+	// Pattern регулярное выражение для строки
+	Pattern string `json:"pattern"`
+	// Type counter — каждое совпадение увеличивает счетчик, gauge — значение берется из группы Group
+	Type string `json:"type,omitempty"`
+	// Group именованная группа с числом для gauge, по умолчанию "value"
+	Group string `json:"group,omitempty"`
+}
+
+// LogFile файл журнала и правила для его строк
+type LogFile struct {
+	Path  string    `json:"path"`
+	Rules []LogRule `json:"rules"`
+}
+
+// LogTailOptions настройки коллектора журналов
+type LogTailOptions struct {
+	Files []LogFile `json:"files"`
+	// Checkpoint файл, в котором сохраняются позиции чтения между перезапусками агента
+	Checkpoint string `json:"checkpoint,omitempty"`
+}
+
+func init() {
+	RegisterCollector(LogTailCollectorName, func(interval time.Duration, options json.RawMessage) (Collector, error) {
+		logTailOptions := LogTailOptions{}
+		if err := decodeOptions(options, &logTailOptions); err != nil {
+			return nil, err
+		}
+		return NewLogTailCollector(interval, logTailOptions)
+	}, false)
+}
+
+type compiledLogRule struct {
+	name    string
+	gauge   bool
+	pattern *regexp.Regexp
+	group   int
+}
+
+// tailedFile состояние чтения одного файла
+type tailedFile struct {
+	path  string
+	rules []compiledLogRule
+
+	file   *os.File
+	info   os.FileInfo
+	offset int64
+	// partial начало строки, для которой еще не записан перевод строки
+	partial []byte
+}
+
+// logCheckpoint позиция чтения файла, сохраняемая между перезапусками
+type logCheckpoint struct {
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+// LogTailCollector читает дописываемые строки файлов журналов и превращает совпадения с правилами в метрики.
+// Ротация отслеживается по inode: старый файл дочитывается, новый читается с начала. При усечении файла
+// чтение начинается заново. Позиции сохраняются в файл Checkpoint, поэтому после перезапуска агента
+// строки не учитываются повторно. Файл, который агент видит впервые, читается с конца.
+type LogTailCollector struct {
+	interval   time.Duration
+	checkpoint string
+
+	mutex       sync.Mutex
+	files       []*tailedFile
+	checkpoints map[string]logCheckpoint
+	counters    map[string]int64
+	gauges      map[string]float64
+}
+
+func NewLogTailCollector(interval time.Duration, options LogTailOptions) (*LogTailCollector, error) {
+	collector := &LogTailCollector{
+		interval:    interval,
+		checkpoint:  options.Checkpoint,
+		checkpoints: make(map[string]logCheckpoint),
+		counters:    make(map[string]int64),
+		gauges:      make(map[string]float64),
+	}
+
+	for _, file := range options.Files {
+		if file.Path == "" {
+			return nil, errors.New("log file path is required")
+		}
+		rules, err := compileLogRules(file.Rules)
+		if err != nil {
+			return nil, fmt.Errorf("log file %s: %v", file.Path, err)
+		}
+		collector.files = append(collector.files, &tailedFile{path: file.Path, rules: rules})
+	}
+
+	if collector.checkpoint != "" {
+		data, err := os.ReadFile(collector.checkpoint)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("error reading log checkpoint: %v", err)
+		}
+		if len(data) > 0 {
+			if err = json.Unmarshal(data, &collector.checkpoints); err != nil {
+				log.Printf("Log checkpoint %s is corrupted and will be ignored: %v", collector.checkpoint, err)
+			}
+		}
+	}
+	return collector, nil
+}
+
+func compileLogRules(rules []LogRule) ([]compiledLogRule, error) {
+	compiled := make([]compiledLogRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return nil, errors.New("rule name is required")
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern of rule %s: %v", rule.Name, err)
+		}
+
+		item := compiledLogRule{name: rule.Name, pattern: pattern}
+		switch rule.Type {
+		case "", models.Counter:
+		case models.Gauge:
+			group := rule.Group
+			if group == "" {
+				group = defaultLogGroup
+			}
+			item.gauge = true
+			item.group = pattern.SubexpIndex(group)
+			if item.group < 0 {
+				return nil, fmt.Errorf("pattern of rule %s has no group %s", rule.Name, group)
+			}
+		default:
+			return nil, fmt.Errorf("unknown type %q of rule %s", rule.Type, rule.Name)
+		}
+		compiled = append(compiled, item)
+	}
+	return compiled, nil
+}
+
+func (c *LogTailCollector) Name() string {
+	return LogTailCollectorName
+}
+
+func (c *LogTailCollector) Interval() time.Duration {
+	return c.interval
+}
+
+func (c *LogTailCollector) Collect(_ context.Context) ([]models.Metrics, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	errs := make([]error, 0)
+	for _, file := range c.files {
+		if err := c.poll(file); err != nil {
+			errs = append(errs, fmt.Errorf("log file %s: %v", file.path, err))
+		}
+	}
+	if err := c.saveCheckpoint(); err != nil {
+		errs = append(errs, err)
+	}
+
+	metrics := make([]models.Metrics, 0, len(c.counters)+len(c.gauges))
+	for _, name := range sortedKeys(c.counters) {
+		metrics = append(metrics, NewCounter(name, c.counters[name]))
+	}
+	for _, name := range sortedKeys(c.gauges) {
+		metrics = append(metrics, NewGauge(name, c.gauges[name]))
+	}
+	c.counters = make(map[string]int64)
+	c.gauges = make(map[string]float64)
+
+	return metrics, errors.Join(errs...)
+}
+
+// poll дочитывает файл, переключаясь на новый файл после ротации
+func (c *LogTailCollector) poll(file *tailedFile) error {
+	info, err := os.Stat(file.path)
+	if errors.Is(err, os.ErrNotExist) {
+		// во время ротации нового файла еще может не быть, старый дочитывается по открытому дескриптору
+		if file.file != nil {
+			return c.readLines(file)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case file.file == nil:
+		return c.open(file, info, c.initialOffset(file.path, info))
+	case !os.SameFile(file.info, info):
+		readErr := c.readLines(file)
+		file.file.Close()
+		file.file = nil
+		return errors.Join(readErr, c.open(file, info, 0))
+	case info.Size() < file.offset:
+		// файл усечен, например через copytruncate
+		if _, err = file.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		file.offset = 0
+		file.partial = nil
+	}
+	return c.readLines(file)
+}
+
+// initialOffset возвращает позицию из сохраненной точки, если файл не менялся.
+// Файл без сохраненной позиции читается с конца, замененный файл — с начала.
+func (c *LogTailCollector) initialOffset(path string, info os.FileInfo) int64 {
+	checkpoint, exists := c.checkpoints[path]
+	if !exists {
+		return info.Size()
+	}
+	// без inode (не unix) оба значения равны 0 и файл считается тем же
+	if checkpoint.Inode == fileInode(info) && checkpoint.Offset <= info.Size() {
+		return checkpoint.Offset
+	}
+	// файл заменили или усекли, пока агент не работал
+	return 0
+}
+
+func (c *LogTailCollector) open(file *tailedFile, info os.FileInfo, offset int64) error {
+	opened, err := os.Open(file.path)
+	if err != nil {
+		return err
+	}
+	// сравнение идет с открытым файлом, его могли заменить между Stat и Open
+	if openedInfo, err := opened.Stat(); err == nil {
+		info = openedInfo
+	}
+	if _, err = opened.Seek(offset, io.SeekStart); err != nil {
+		opened.Close()
+		return err
+	}
+
+	file.file = opened
+	file.info = info
+	file.offset = offset
+	file.partial = nil
+	return c.readLines(file)
+}
+
+func (c *LogTailCollector) readLines(file *tailedFile) error {
+	buffer := make([]byte, logReadChunkSize)
+	for {
+		n, err := file.file.Read(buffer)
+		if n > 0 {
+			file.offset += int64(n)
+			c.consume(file, buffer[:n])
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *LogTailCollector) consume(file *tailedFile, chunk []byte) {
+	data := append(file.partial, chunk...)
+	for {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			break
+		}
+		c.match(file, string(bytes.TrimSuffix(data[:end], []byte("\r"))))
+		data = data[end+1:]
+	}
+
+	if len(data) > maxLogLineSize {
+		c.match(file, string(data))
+		data = nil
+	}
+	file.partial = append([]byte(nil), data...)
+}
+
+func (c *LogTailCollector) match(file *tailedFile, line string) {
+	for _, rule := range file.rules {
+		if !rule.gauge {
+			if rule.pattern.MatchString(line) {
+				c.counters[rule.name]++
+			}
+			continue
+		}
+
+		groups := rule.pattern.FindStringSubmatch(line)
+		if groups == nil {
+			continue
+		}
+		if value, err := strconv.ParseFloat(groups[rule.group], 64); err == nil {
+			c.gauges[rule.name] = value
+		}
+	}
+}
+
+// saveCheckpoint сохраняет позиции полностью прочитанных строк
+func (c *LogTailCollector) saveCheckpoint() error {
+	if c.checkpoint == "" {
+		return nil
+	}
+
+	for _, file := range c.files {
+		if file.file == nil {
+			continue
+		}
+		c.checkpoints[file.path] = logCheckpoint{
+			Inode:  fileInode(file.info),
+			Offset: file.offset - int64(len(file.partial)),
+		}
+	}
+
+	data, err := json.Marshal(c.checkpoints)
+	if err != nil {
+		return err
+	}
+	tmp := c.checkpoint + ".tmp"
+	if err = os.MkdirAll(filepath.Dir(c.checkpoint), 0755); err != nil {
+		return fmt.Errorf("error saving log checkpoint: %v", err)
+	}
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("error saving log checkpoint: %v", err)
+	}
+	if err = os.Rename(tmp, c.checkpoint); err != nil {
+		return fmt.Errorf("error saving log checkpoint: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package agent
+
+import "os"
+
+// fileInode на системах без inode возвращает 0, ротация определяется только через os.SameFile
+func fileInode(_ os.FileInfo) uint64 {
+	return 0
+}
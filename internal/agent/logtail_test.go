@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogRules = []LogRule{
+	{Name: "AppErrors", Pattern: "ERROR"},
+	{Name: "AppLatency", Pattern: `latency=(?P<value>[0-9.]+)ms`, Type: "gauge"},
+}
+
+func appendLog(t *testing.T, path string, lines string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = file.WriteString(lines)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+}
+
+func collectLog(t *testing.T, collector *LogTailCollector) (map[string]int64, map[string]float64) {
+	t.Helper()
+	metrics, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+	return counterValues(metrics), gaugeValues(metrics)
+}
+
+func TestLogTailCollector_Collect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLog(t, path, "ERROR old line before start\n")
+
+	collector, err := NewLogTailCollector(time.Second, LogTailOptions{Files: []LogFile{{Path: path, Rules: testLogRules}}})
+	require.NoError(t, err)
+
+	// файл, который агент видит впервые, читается с конца
+	counters, gauges := collectLog(t, collector)
+	assert.Empty(t, counters)
+	assert.Empty(t, gauges)
+
+	appendLog(t, path, "INFO request latency=12.5ms\nERROR failed\nERROR fail")
+	counters, gauges = collectLog(t, collector)
+	assert.Equal(t, map[string]int64{"AppErrors": 1}, counters)
+	assert.Equal(t, map[string]float64{"AppLatency": 12.5}, gauges)
+
+	// строка учитывается, когда дописан перевод строки
+	appendLog(t, path, "ed again\nINFO latency=3ms\n")
+	counters, gauges = collectLog(t, collector)
+	assert.Equal(t, map[string]int64{"AppErrors": 1}, counters)
+	assert.Equal(t, map[string]float64{"AppLatency": 3}, gauges)
+}
+
+func TestLogTailCollector_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	appendLog(t, path, "")
+
+	collector, err := NewLogTailCollector(time.Second, LogTailOptions{Files: []LogFile{{Path: path, Rules: testLogRules}}})
+	require.NoError(t, err)
+	collectLog(t, collector)
+
+	// строки, дописанные в старый файл перед ротацией, тоже учитываются
+	appendLog(t, path, "ERROR before rotation\n")
+	require.NoError(t, os.Rename(path, filepath.Join(dir, "app.log.1")))
+	appendLog(t, path, "ERROR after rotation\nERROR after rotation\n")
+
+	counters, _ := collectLog(t, collector)
+	assert.Equal(t, map[string]int64{"AppErrors": 3}, counters)
+
+	// усечение файла
+	require.NoError(t, os.Truncate(path, 0))
+	appendLog(t, path, "ERROR after truncate\n")
+
+	counters, _ = collectLog(t, collector)
+	assert.Equal(t, map[string]int64{"AppErrors": 1}, counters)
+}
+
+func TestLogTailCollector_Checkpoint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	options := LogTailOptions{
+		Files:      []LogFile{{Path: path, Rules: testLogRules}},
+		Checkpoint: filepath.Join(dir, "state", "logtail.json"),
+	}
+	appendLog(t, path, "")
+
+	collector, err := NewLogTailCollector(time.Second, options)
+	require.NoError(t, err)
+	collectLog(t, collector)
+
+	appendLog(t, path, "ERROR one\nERROR tw")
+	counters, _ := collectLog(t, collector)
+	assert.Equal(t, map[string]int64{"AppErrors": 1}, counters)
+
+	// после перезапуска чтение продолжается с сохраненной позиции, незаконченная строка читается заново
+	appendLog(t, path, "o\nERROR three\n")
+	restarted, err := NewLogTailCollector(time.Second, options)
+	require.NoError(t, err)
+
+	counters, _ = collectLog(t, restarted)
+	assert.Equal(t, map[string]int64{"AppErrors": 2}, counters)
+}
+
+func TestNewLogTailCollector_InvalidRules(t *testing.T) {
+	invalid := []LogRule{
+		{Pattern: "ERROR"},
+		{Name: "Broken", Pattern: "("},
+		{Name: "NoGroup", Pattern: "latency=([0-9]+)", Type: "gauge"},
+		{Name: "Unknown", Pattern: "x", Type: "histogram"},
+	}
+	for _, rule := range invalid {
+		_, err := NewLogTailCollector(time.Second, LogTailOptions{Files: []LogFile{{Path: "app.log", Rules: []LogRule{rule}}}})
+		assert.Error(t, err, rule.Name)
+	}
+}
@@ -1,10 +1,13 @@
 package agent
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"math/rand"
 	"runtime"
+	"time"
 
 	"github.com/Bessima/metrics-collect/internal/common"
 	models "github.com/Bessima/metrics-collect/internal/model"
@@ -15,114 +18,135 @@ import (
 const CounterPollCountMetric = "PollCount"
 const GaugeRandomMetric = "RandomValue"
 
+const (
+	MemStatsCollectorName = "memstats"
+	PollCollectorName     = "poll"
+	MemoryCollectorName   = "memory"
+)
+
+func init() {
+	RegisterCollector(MemStatsCollectorName, func(interval time.Duration, _ json.RawMessage) (Collector, error) {
+		return &MemStatsCollector{interval: interval}, nil
+	}, true)
+	RegisterCollector(PollCollectorName, func(interval time.Duration, _ json.RawMessage) (Collector, error) {
+		return &PollCollector{interval: interval}, nil
+	}, true)
+	RegisterCollector(MemoryCollectorName, func(interval time.Duration, _ json.RawMessage) (Collector, error) {
+		return &MemoryCollector{interval: interval}, nil
+	}, true)
+}
+
 func GetAllMemStats() map[string]any {
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
 
 	return map[string]any{
-		"Alloc":           m.Alloc,
-		"BuckHashSys":     m.BuckHashSys,
-		"Frees":           m.Frees,
-		"GCCPUFraction":   m.GCCPUFraction,
-		"GCSys":           m.GCSys,
-		"HeapAlloc":       m.HeapAlloc,
-		"HeapIdle":        m.HeapIdle,
-		"HeapInuse":       m.HeapInuse,
-		"HeapObjects":     m.HeapObjects,
-		"HeapReleased":    m.HeapReleased,
-		"HeapSys":         m.HeapSys,
-		"LastGC":          m.LastGC,
-		"Lookups":         m.Lookups,
-		"MCacheInuse":     m.MCacheInuse,
-		"MCacheSys":       m.MCacheSys,
-		"MSpanInuse":      m.MSpanInuse,
-		"MSpanSys":        m.MSpanSys,
-		"Mallocs":         m.Mallocs,
-		"NextGC":          m.NextGC,
-		"NumForcedGC":     m.NumForcedGC,
-		"NumGC":           m.NumGC,
-		"OtherSys":        m.OtherSys,
-		"PauseTotalNs":    m.PauseTotalNs,
-		"StackInuse":      m.StackInuse,
-		"StackSys":        m.StackSys,
-		"Sys":             m.Sys,
-		"TotalAlloc":      m.TotalAlloc,
-		GaugeRandomMetric: rand.Int63(),
+		"Alloc":         m.Alloc,
+		"BuckHashSys":   m.BuckHashSys,
+		"Frees":         m.Frees,
+		"GCCPUFraction": m.GCCPUFraction,
+		"GCSys":         m.GCSys,
+		"HeapAlloc":     m.HeapAlloc,
+		"HeapIdle":      m.HeapIdle,
+		"HeapInuse":     m.HeapInuse,
+		"HeapObjects":   m.HeapObjects,
+		"HeapReleased":  m.HeapReleased,
+		"HeapSys":       m.HeapSys,
+		"LastGC":        m.LastGC,
+		"Lookups":       m.Lookups,
+		"MCacheInuse":   m.MCacheInuse,
+		"MCacheSys":     m.MCacheSys,
+		"MSpanInuse":    m.MSpanInuse,
+		"MSpanSys":      m.MSpanSys,
+		"Mallocs":       m.Mallocs,
+		"NextGC":        m.NextGC,
+		"NumForcedGC":   m.NumForcedGC,
+		"NumGC":         m.NumGC,
+		"OtherSys":      m.OtherSys,
+		"PauseTotalNs":  m.PauseTotalNs,
+		"StackInuse":    m.StackInuse,
+		"StackSys":      m.StackSys,
+		"Sys":           m.Sys,
+		"TotalAlloc":    m.TotalAlloc,
 	}
 }
 
-func AddPoolCounter(metrics chan models.Metrics, poolCount int64) error {
-	value, err := common.ConvertInterfaceToStr(poolCount)
-	if err != nil {
-		return fmt.Errorf("error converting interface to metric %s: %v", CounterPollCountMetric, err)
-	}
-	metric, err := GetMetric(repository.TypeCounter, CounterPollCountMetric, value)
-	if err != nil {
-		return fmt.Errorf("error getting object metric %s: %v", CounterPollCountMetric, err)
-	}
-	metrics <- metric
-	return nil
-}
+// gaugesFromMap преобразует набор значений в метрики типа gauge
+func gaugesFromMap(values map[string]any) ([]models.Metrics, error) {
+	metrics := make([]models.Metrics, 0, len(values))
+	errs := make([]error, 0)
 
-func AddMemStats(metrics chan models.Metrics) []error {
-	errors := make([]error, 0)
-	for name, anyValue := range GetAllMemStats() {
+	for name, anyValue := range values {
 		value, err := common.ConvertInterfaceToStr(anyValue)
 		if err != nil {
-			errors = append(
-				errors,
-				fmt.Errorf("error converting interface to metric %s: %v", name, err),
-			)
+			errs = append(errs, fmt.Errorf("error converting interface to metric %s: %v", name, err))
 			continue
 		}
 		metric, err := GetMetric(repository.TypeGauge, name, value)
 		if err != nil {
-			errors = append(
-				errors,
-				fmt.Errorf("error getting object metric %s: %v", CounterPollCountMetric, err),
-			)
+			errs = append(errs, fmt.Errorf("error getting object metric %s: %v", name, err))
 			continue
 		}
-		metrics <- metric
+		metrics = append(metrics, metric)
 	}
-	return errors
+	return metrics, errors.Join(errs...)
 }
 
-func AddBaseMetrics(metrics chan models.Metrics, poolCount int64) {
-	errors := AddMemStats(metrics)
-	err := AddPoolCounter(metrics, poolCount)
-	if err != nil {
-		errors = append(errors, err)
-	}
+// MemStatsCollector собирает метрики runtime.MemStats
+type MemStatsCollector struct {
+	interval time.Duration
+}
 
-	for _, err = range errors {
-		log.Println(err)
-	}
+func (c *MemStatsCollector) Name() string {
+	return MemStatsCollectorName
 }
 
-func AdditionalMemMetrics(metrics chan models.Metrics) {
-	v, err := mem.VirtualMemory()
-	if err != nil || v == nil {
-		log.Printf("Error getting additional metrics %v", err)
-		return
-	}
+func (c *MemStatsCollector) Interval() time.Duration {
+	return c.interval
+}
 
-	newMetrics := map[string]any{"TotalMemory": v.Total, "FreeMemory": v.Free, "CPUutilization1": v.UsedPercent}
+func (c *MemStatsCollector) Collect(_ context.Context) ([]models.Metrics, error) {
+	return gaugesFromMap(GetAllMemStats())
+}
 
-	for name, anyValue := range newMetrics {
-		value, err := common.ConvertInterfaceToStr(anyValue)
-		if err != nil {
-			log.Printf("Error converting interface to metric %s: %v", name, err)
-			continue
-		}
-		metric, err := GetMetric(repository.TypeGauge, name, value)
-		if err != nil {
-			log.Printf("Error getting object metric %s: %v", CounterPollCountMetric, err)
-			return
-		}
-		metrics <- metric
-	}
+// PollCollector считает количество опросов и отдает случайное значение
+type PollCollector struct {
+	interval time.Duration
+}
+
+func (c *PollCollector) Name() string {
+	return PollCollectorName
+}
+
+func (c *PollCollector) Interval() time.Duration {
+	return c.interval
+}
+
+func (c *PollCollector) Collect(_ context.Context) ([]models.Metrics, error) {
+	return []models.Metrics{
+		NewCounter(CounterPollCountMetric, 1),
+		NewGauge(GaugeRandomMetric, float64(rand.Int63())),
+	}, nil
+}
+
+// MemoryCollector собирает метрики виртуальной памяти системы
+type MemoryCollector struct {
+	interval time.Duration
+}
+
+func (c *MemoryCollector) Name() string {
+	return MemoryCollectorName
+}
 
-	log.Print("Add additional metrics")
+func (c *MemoryCollector) Interval() time.Duration {
+	return c.interval
+}
+
+func (c *MemoryCollector) Collect(ctx context.Context) ([]models.Metrics, error) {
+	v, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil || v == nil {
+		return nil, fmt.Errorf("error getting additional metrics %v", err)
+	}
 
+	return gaugesFromMap(map[string]any{"TotalMemory": v.Total, "FreeMemory": v.Free})
 }
@@ -1,29 +1,48 @@
 package agent
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	models "github.com/Bessima/metrics-collect/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func BenchmarkAddBaseMetricsAddBaseMetrics(b *testing.B) {
-	metricsForSend := make(chan models.Metrics, 100)
-
-	// Запускаем горутину для чтения из канала
-	done := make(chan bool)
-	go func() {
-		for range metricsForSend {
-			// Просто читаем и выбрасываем
-		}
-		done <- true
-	}()
+func BenchmarkMemStatsCollector_Collect(b *testing.B) {
+	collector := &MemStatsCollector{interval: time.Second}
+	ctx := context.Background()
 
 	b.ResetTimer()
 	for n := 0; n < b.N; n++ {
-		AddBaseMetrics(metricsForSend, 1)
+		if _, err := collector.Collect(ctx); err != nil {
+			b.Fatal(err)
+		}
 	}
-	b.StopTimer()
+}
+
+func TestMemStatsCollector_Collect(t *testing.T) {
+	collector := &MemStatsCollector{interval: time.Second}
+
+	metrics, err := collector.Collect(context.Background())
+
+	require.NoError(t, err)
+	assert.Len(t, metrics, len(GetAllMemStats()))
+	for _, metric := range metrics {
+		assert.Equal(t, models.Gauge, metric.MType)
+		assert.NotNil(t, metric.Value)
+	}
+}
+
+func TestPollCollector_Collect(t *testing.T) {
+	collector := &PollCollector{interval: time.Second}
+
+	metrics, err := collector.Collect(context.Background())
 
-	close(metricsForSend)
-	<-done
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, CounterPollCountMetric, metrics[0].ID)
+	assert.Equal(t, int64(1), *metrics[0].Delta)
+	assert.Equal(t, GaugeRandomMetric, metrics[1].ID)
 }
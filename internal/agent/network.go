@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+	psnet "github.com/shirou/gopsutil/v4/net"
+)
+
+const NetworkCollectorName = "network"
+
+// tcpStates состояния TCP-соединений, которые отправляются всегда, даже с нулевым количеством
+var tcpStates = []string{
+	"ESTABLISHED", "SYN_SENT", "SYN_RECV", "FIN_WAIT1", "FIN_WAIT2", "TIME_WAIT",
+	"CLOSE", "CLOSE_WAIT", "LAST_ACK", "LISTEN", "CLOSING",
+}
+
+// NetworkOptions настройки сетевого коллектора
+type NetworkOptions struct {
+	// Interfaces шаблоны имен сетевых интерфейсов
+	Interfaces PatternFilter `json:"interfaces"`
+	// TCPStates включает подсчет TCP-соединений по состояниям
+	TCPStates bool `json:"tcp_states"`
+}
+
+func defaultNetworkOptions() NetworkOptions {
+	return NetworkOptions{
+		Interfaces: PatternFilter{Exclude: []string{"lo", "veth*"}},
+		TCPStates:  true,
+	}
+}
+
+func init() {
+	RegisterCollector(NetworkCollectorName, func(interval time.Duration, options json.RawMessage) (Collector, error) {
+		networkOptions := defaultNetworkOptions()
+		if err := decodeOptions(options, &networkOptions); err != nil {
+			return nil, err
+		}
+		return NewNetworkCollector(interval, networkOptions), nil
+	}, false)
+}
+
+// NetworkCollector собирает счетчики сетевых интерфейсов и количество TCP-соединений по состояниям
+type NetworkCollector struct {
+	interval time.Duration
+	options  NetworkOptions
+	counters *cumulativeCounters
+
+	ioCounters  func(ctx context.Context, pernic bool) ([]psnet.IOCountersStat, error)
+	connections func(ctx context.Context, kind string) ([]psnet.ConnectionStat, error)
+}
+
+func NewNetworkCollector(interval time.Duration, options NetworkOptions) *NetworkCollector {
+	return &NetworkCollector{
+		interval:    interval,
+		options:     options,
+		counters:    newCumulativeCounters(),
+		ioCounters:  psnet.IOCountersWithContext,
+		connections: psnet.ConnectionsWithContext,
+	}
+}
+
+func (c *NetworkCollector) Name() string {
+	return NetworkCollectorName
+}
+
+func (c *NetworkCollector) Interval() time.Duration {
+	return c.interval
+}
+
+func (c *NetworkCollector) Collect(ctx context.Context) ([]models.Metrics, error) {
+	metrics, ioErr := c.collectInterfaces(ctx)
+
+	var connErr error
+	if c.options.TCPStates {
+		var connMetrics []models.Metrics
+		connMetrics, connErr = c.collectConnections(ctx)
+		metrics = append(metrics, connMetrics...)
+	}
+
+	return metrics, errors.Join(ioErr, connErr)
+}
+
+func (c *NetworkCollector) collectInterfaces(ctx context.Context) ([]models.Metrics, error) {
+	stats, err := c.ioCounters(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("error getting network io counters: %v", err)
+	}
+
+	metrics := make([]models.Metrics, 0, len(stats)*8)
+	for _, stat := range stats {
+		if !c.options.Interfaces.Allowed(stat.Name) {
+			continue
+		}
+		values := []struct {
+			name  string
+			value uint64
+		}{
+			{labeledName("NetBytesSent", stat.Name), stat.BytesSent},
+			{labeledName("NetBytesRecv", stat.Name), stat.BytesRecv},
+			{labeledName("NetPacketsSent", stat.Name), stat.PacketsSent},
+			{labeledName("NetPacketsRecv", stat.Name), stat.PacketsRecv},
+			{labeledName("NetErrIn", stat.Name), stat.Errin},
+			{labeledName("NetErrOut", stat.Name), stat.Errout},
+			{labeledName("NetDropIn", stat.Name), stat.Dropin},
+			{labeledName("NetDropOut", stat.Name), stat.Dropout},
+		}
+		for _, item := range values {
+			if delta, ok := c.counters.delta(item.name, item.value); ok {
+				metrics = append(metrics, NewCounter(item.name, delta))
+			}
+		}
+	}
+	return metrics, nil
+}
+
+func (c *NetworkCollector) collectConnections(ctx context.Context) ([]models.Metrics, error) {
+	connections, err := c.connections(ctx, "tcp")
+	if err != nil {
+		return nil, fmt.Errorf("error getting tcp connections: %v", err)
+	}
+
+	counts := make(map[string]int, len(tcpStates))
+	for _, state := range tcpStates {
+		counts[state] = 0
+	}
+	for _, connection := range connections {
+		if connection.Status == "" || connection.Status == "NONE" {
+			continue
+		}
+		counts[connection.Status]++
+	}
+
+	states := make([]string, 0, len(counts))
+	for state := range counts {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+
+	metrics := make([]models.Metrics, 0, len(states))
+	for _, state := range states {
+		metrics = append(metrics, NewGauge(labeledName("TCPConnections", state), float64(counts[state])))
+	}
+	return metrics, nil
+}
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	psnet "github.com/shirou/gopsutil/v4/net"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkCollector_Collect(t *testing.T) {
+	samples := [][]psnet.IOCountersStat{
+		{
+			{Name: "eth0", BytesSent: 1000, BytesRecv: 5000, PacketsSent: 10, PacketsRecv: 50, Errin: 1, Dropout: 2},
+			{Name: "lo", BytesSent: 100},
+			{Name: "veth1a2b", BytesSent: 100},
+		},
+		{
+			{Name: "eth0", BytesSent: 1600, BytesRecv: 5200, PacketsSent: 16, PacketsRecv: 52, Errin: 3, Dropout: 2},
+			{Name: "lo", BytesSent: 200},
+			{Name: "veth1a2b", BytesSent: 300},
+		},
+	}
+	call := 0
+
+	collector := NewNetworkCollector(time.Second, defaultNetworkOptions())
+	collector.ioCounters = func(_ context.Context, _ bool) ([]psnet.IOCountersStat, error) {
+		sample := samples[call]
+		call++
+		return sample, nil
+	}
+	collector.connections = func(_ context.Context, _ string) ([]psnet.ConnectionStat, error) {
+		return []psnet.ConnectionStat{
+			{Status: "ESTABLISHED"},
+			{Status: "ESTABLISHED"},
+			{Status: "LISTEN"},
+		}, nil
+	}
+
+	first, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, counterValues(first))
+
+	gauges := gaugeValues(first)
+	assert.Equal(t, 2.0, gauges["TCPConnections_ESTABLISHED"])
+	assert.Equal(t, 1.0, gauges["TCPConnections_LISTEN"])
+	assert.Equal(t, 0.0, gauges["TCPConnections_TIME_WAIT"])
+
+	second, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+
+	counters := counterValues(second)
+	assert.Equal(t, int64(600), counters["NetBytesSent_eth0"])
+	assert.Equal(t, int64(200), counters["NetBytesRecv_eth0"])
+	assert.Equal(t, int64(6), counters["NetPacketsSent_eth0"])
+	assert.Equal(t, int64(2), counters["NetErrIn_eth0"])
+	assert.Equal(t, int64(0), counters["NetDropOut_eth0"])
+	assert.NotContains(t, counters, "NetBytesSent_lo")
+	assert.NotContains(t, counters, "NetBytesSent_veth1a2b")
+}
+
+func TestNetworkCollector_WithoutTCPStates(t *testing.T) {
+	options := defaultNetworkOptions()
+	options.TCPStates = false
+
+	collector := NewNetworkCollector(time.Second, options)
+	collector.ioCounters = func(_ context.Context, _ bool) ([]psnet.IOCountersStat, error) {
+		return nil, nil
+	}
+	collector.connections = func(_ context.Context, _ string) ([]psnet.ConnectionStat, error) {
+		t.Fatal("connections should not be requested")
+		return nil, nil
+	}
+
+	metrics, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, metrics)
+}
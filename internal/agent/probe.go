@@ -0,0 +1,248 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+)
+
+const ProbeCollectorName = "probe"
+
+const (
+	defaultProbeTimeout = 10 * time.Second
+	// maxProbeBodySize сколько байт ответа читается, чтобы учесть время передачи тела
+	maxProbeBodySize = 1024 * 1024
+)
+
+// ProbeTarget проверяемый адрес: URL для HTTP-проверки или host:port для TCP-проверки
+type ProbeTarget struct {
+	Name string `json:"name"`
+	// URL адрес HTTP или HTTPS
+	URL string `json:"url,omitempty"`
+	// Address адрес host:port для проверки TCP-соединения
+	Address string `json:"address,omitempty"`
+	// Timeout ограничение времени проверки
+	Timeout Duration `json:"timeout,omitempty"`
+	// TLSSkipVerify отключает проверку сертификата, срок его действия все равно отправляется
+	TLSSkipVerify bool `json:"tls_skip_verify,omitempty"`
+}
+
+// ProbeOptions настройки коллектора проверок доступности
+type ProbeOptions struct {
+	Targets []ProbeTarget `json:"targets"`
+}
+
+func init() {
+	RegisterCollector(ProbeCollectorName, func(interval time.Duration, options json.RawMessage) (Collector, error) {
+		probeOptions := ProbeOptions{}
+		if err := decodeOptions(options, &probeOptions); err != nil {
+			return nil, err
+		}
+		return NewProbeCollector(interval, probeOptions)
+	}, false)
+}
+
+// ProbeCollector проверяет доступность HTTP- и TCP-адресов, как blackbox_exporter.
+// Для каждой цели отправляется признак успеха ProbeSuccess (0 или 1) и общее время ProbeDuration,
+// для HTTP — время этапов запроса, код ответа и число дней до окончания срока сертификата.
+type ProbeCollector struct {
+	interval time.Duration
+	targets  []ProbeTarget
+	now      func() time.Time
+}
+
+func NewProbeCollector(interval time.Duration, options ProbeOptions) (*ProbeCollector, error) {
+	targets := make([]ProbeTarget, 0, len(options.Targets))
+	names := make(map[string]bool, len(options.Targets))
+
+	for _, target := range options.Targets {
+		if target.Name == "" {
+			return nil, errors.New("probe name is required")
+		}
+		if names[target.Name] {
+			return nil, fmt.Errorf("duplicate probe %s", target.Name)
+		}
+		names[target.Name] = true
+
+		if (target.URL == "") == (target.Address == "") {
+			return nil, fmt.Errorf("probe %s needs either url or address", target.Name)
+		}
+		if target.URL != "" {
+			parsed, err := url.Parse(target.URL)
+			if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+				return nil, fmt.Errorf("invalid url of probe %s", target.Name)
+			}
+		}
+		if target.Address != "" {
+			if _, _, err := net.SplitHostPort(target.Address); err != nil {
+				return nil, fmt.Errorf("invalid address of probe %s: %v", target.Name, err)
+			}
+		}
+		if target.Timeout <= 0 {
+			target.Timeout = Duration(defaultProbeTimeout)
+		}
+		targets = append(targets, target)
+	}
+
+	return &ProbeCollector{interval: interval, targets: targets, now: time.Now}, nil
+}
+
+func (c *ProbeCollector) Name() string {
+	return ProbeCollectorName
+}
+
+func (c *ProbeCollector) Interval() time.Duration {
+	return c.interval
+}
+
+func (c *ProbeCollector) Collect(ctx context.Context) ([]models.Metrics, error) {
+	results := make([][]models.Metrics, len(c.targets))
+	errs := make([]error, len(c.targets))
+
+	var wg sync.WaitGroup
+	for i, target := range c.targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = c.probe(ctx, target)
+		}()
+	}
+	wg.Wait()
+
+	metrics := make([]models.Metrics, 0)
+	for _, result := range results {
+		metrics = append(metrics, result...)
+	}
+	return metrics, errors.Join(errs...)
+}
+
+func (c *ProbeCollector) probe(ctx context.Context, target ProbeTarget) ([]models.Metrics, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(target.Timeout))
+	defer cancel()
+
+	var metrics []models.Metrics
+	var err error
+	started := c.now()
+	if target.URL != "" {
+		metrics, err = c.probeHTTP(ctx, target)
+	} else {
+		metrics, err = c.probeTCP(ctx, target)
+	}
+
+	success := 1.0
+	if err != nil {
+		success = 0
+		err = fmt.Errorf("probe %s: %v", target.Name, err)
+	}
+	return append(metrics,
+		NewGauge(labeledName("ProbeSuccess", target.Name), success),
+		NewGauge(labeledName("ProbeDuration", target.Name), c.now().Sub(started).Seconds()),
+	), err
+}
+
+func (c *ProbeCollector) probeTCP(ctx context.Context, target ProbeTarget) ([]models.Metrics, error) {
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", target.Address)
+	if err != nil {
+		return nil, err
+	}
+	return nil, conn.Close()
+}
+
+// probeTimings отметки времени этапов HTTP-запроса из httptrace
+type probeTimings struct {
+	mutex        sync.Mutex
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+}
+
+func (timings *probeTimings) set(field *time.Time, at time.Time) {
+	timings.mutex.Lock()
+	defer timings.mutex.Unlock()
+	*field = at
+}
+
+func phaseSeconds(start time.Time, end time.Time) float64 {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start).Seconds()
+}
+
+func (c *ProbeCollector) probeHTTP(ctx context.Context, target ProbeTarget) ([]models.Metrics, error) {
+	timings := &probeTimings{}
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { timings.set(&timings.dnsStart, c.now()) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { timings.set(&timings.dnsDone, c.now()) },
+		ConnectStart:         func(string, string) { timings.set(&timings.connectStart, c.now()) },
+		ConnectDone:          func(string, string, error) { timings.set(&timings.connectDone, c.now()) },
+		TLSHandshakeStart:    func() { timings.set(&timings.tlsStart, c.now()) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { timings.set(&timings.tlsDone, c.now()) },
+		GotFirstResponseByte: func() { timings.set(&timings.firstByte, c.now()) },
+	}
+
+	request, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, target.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// каждая проверка открывает новое соединение, иначе этапы соединения не измерялись бы
+	client := &http.Client{
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: target.TLSSkipVerify},
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	started := c.now()
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if _, err = io.Copy(io.Discard, io.LimitReader(response.Body, maxProbeBodySize)); err != nil {
+		return nil, fmt.Errorf("error reading body: %v", err)
+	}
+
+	timings.mutex.Lock()
+	defer timings.mutex.Unlock()
+
+	metrics := []models.Metrics{
+		NewGauge(labeledName("ProbeStatusCode", target.Name), float64(response.StatusCode)),
+		NewGauge(labeledName("ProbeDNSSeconds", target.Name), phaseSeconds(timings.dnsStart, timings.dnsDone)),
+		NewGauge(labeledName("ProbeConnectSeconds", target.Name), phaseSeconds(timings.connectStart, timings.connectDone)),
+		NewGauge(labeledName("ProbeFirstByteSeconds", target.Name), phaseSeconds(started, timings.firstByte)),
+	}
+	if response.TLS != nil {
+		metrics = append(metrics, NewGauge(labeledName("ProbeTLSSeconds", target.Name), phaseSeconds(timings.tlsStart, timings.tlsDone)))
+		if len(response.TLS.PeerCertificates) > 0 {
+			expiry := response.TLS.PeerCertificates[0].NotAfter.Sub(c.now()).Hours() / 24
+			metrics = append(metrics, NewGauge(labeledName("ProbeCertExpiryDays", target.Name), expiry))
+		}
+	}
+
+	if response.StatusCode >= http.StatusBadRequest {
+		return metrics, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	return metrics, nil
+}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeCollector_HTTP(t *testing.T) {
+	ok := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	collector, err := NewProbeCollector(time.Second, ProbeOptions{Targets: []ProbeTarget{
+		{Name: "api", URL: ok.URL + "/health", TLSSkipVerify: true},
+		{Name: "backend", URL: failing.URL},
+	}})
+	require.NoError(t, err)
+
+	metrics, err := collector.Collect(context.Background())
+	assert.ErrorContains(t, err, "probe backend")
+
+	gauges := gaugeValues(metrics)
+	assert.Equal(t, 1.0, gauges["ProbeSuccess_api"])
+	assert.Equal(t, 200.0, gauges["ProbeStatusCode_api"])
+	assert.Greater(t, gauges["ProbeDuration_api"], 0.0)
+	assert.Greater(t, gauges["ProbeConnectSeconds_api"], 0.0)
+	assert.Greater(t, gauges["ProbeTLSSeconds_api"], 0.0)
+	assert.Greater(t, gauges["ProbeFirstByteSeconds_api"], 0.0)
+	assert.Contains(t, gauges, "ProbeDNSSeconds_api")
+	// сертификат httptest действует несколько десятков лет
+	assert.Greater(t, gauges["ProbeCertExpiryDays_api"], 365.0)
+
+	assert.Equal(t, 0.0, gauges["ProbeSuccess_backend"])
+	assert.Equal(t, 503.0, gauges["ProbeStatusCode_backend"])
+	assert.NotContains(t, gauges, "ProbeTLSSeconds_backend")
+}
+
+func TestProbeCollector_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddress := closed.Addr().String()
+	closed.Close()
+
+	collector, err := NewProbeCollector(time.Second, ProbeOptions{Targets: []ProbeTarget{
+		{Name: "postgres", Address: listener.Addr().String()},
+		{Name: "redis", Address: closedAddress, Timeout: Duration(time.Second)},
+	}})
+	require.NoError(t, err)
+
+	metrics, err := collector.Collect(context.Background())
+	assert.ErrorContains(t, err, "probe redis")
+
+	gauges := gaugeValues(metrics)
+	assert.Equal(t, 1.0, gauges["ProbeSuccess_postgres"])
+	assert.Contains(t, gauges, "ProbeDuration_postgres")
+	assert.Equal(t, 0.0, gauges["ProbeSuccess_redis"])
+}
+
+func TestProbeCollector_Timeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	collector, err := NewProbeCollector(time.Second, ProbeOptions{Targets: []ProbeTarget{
+		{Name: "slow", URL: slow.URL, Timeout: Duration(50 * time.Millisecond)},
+	}})
+	require.NoError(t, err)
+
+	metrics, err := collector.Collect(context.Background())
+	assert.Error(t, err)
+
+	gauges := gaugeValues(metrics)
+	assert.Equal(t, 0.0, gauges["ProbeSuccess_slow"])
+	assert.Less(t, gauges["ProbeDuration_slow"], 0.5)
+}
+
+func TestNewProbeCollector_InvalidTargets(t *testing.T) {
+	invalid := []ProbeTarget{
+		{URL: "http://localhost"},
+		{Name: "both", URL: "http://localhost", Address: "localhost:80"},
+		{Name: "none"},
+		{Name: "scheme", URL: "ftp://localhost"},
+		{Name: "port", Address: "localhost"},
+	}
+	for _, target := range invalid {
+		_, err := NewProbeCollector(time.Second, ProbeOptions{Targets: []ProbeTarget{target}})
+		assert.Error(t, err, target.Name)
+	}
+}
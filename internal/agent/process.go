@@ -0,0 +1,341 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+const ProcessCollectorName = "process"
+
+// ProcessSelector описывает группу отслеживаемых процессов.
+// Процесс попадает в группу, если подходит под все заданные условия.
+type ProcessSelector struct {
+	// Name имя группы, используется в именах метрик
+	Name string `json:"name"`
+	// NamePattern регулярное выражение для имени процесса
+	NamePattern string `json:"name_pattern,omitempty"`
+	// Cmdline подстрока командной строки процесса
+	Cmdline string `json:"cmdline,omitempty"`
+	// Pidfile путь к файлу с pid процесса
+	Pidfile string `json:"pidfile,omitempty"`
+}
+
+// ProcessOptions настройки коллектора процессов
+type ProcessOptions struct {
+	Processes []ProcessSelector `json:"processes"`
+}
+
+func init() {
+	RegisterCollector(ProcessCollectorName, func(interval time.Duration, options json.RawMessage) (Collector, error) {
+		processOptions := ProcessOptions{}
+		if err := decodeOptions(options, &processOptions); err != nil {
+			return nil, err
+		}
+		return NewProcessCollector(interval, processOptions)
+	}, false)
+}
+
+// processInfo сведения о процессе, нужные для отбора по селектору
+type processInfo struct {
+	pid     int32
+	name    string
+	cmdline string
+}
+
+// processStats показатели процесса на момент опроса
+type processStats struct {
+	createTime int64
+	rss        uint64
+	cpuSeconds float64
+	openFDs    int32
+	threads    int32
+}
+
+type processCPUSample struct {
+	cpuSeconds float64
+	at         time.Time
+}
+
+type compiledSelector struct {
+	ProcessSelector
+	namePattern *regexp.Regexp
+}
+
+// ProcessCollector собирает показатели процессов, выбранных по имени, командной строке или pid-файлу.
+// Процессы одной группы нумеруются по времени запуска, поэтому имена метрик не зависят от pid.
+type ProcessCollector struct {
+	interval  time.Duration
+	selectors []compiledSelector
+
+	mutex   sync.Mutex
+	lastCPU map[int32]processCPUSample
+
+	now      func() time.Time
+	list     func(ctx context.Context) ([]processInfo, error)
+	describe func(ctx context.Context, pid int32) (processInfo, error)
+	stats    func(ctx context.Context, pid int32) (processStats, error)
+}
+
+func NewProcessCollector(interval time.Duration, options ProcessOptions) (*ProcessCollector, error) {
+	selectors := make([]compiledSelector, 0, len(options.Processes))
+	names := make(map[string]bool, len(options.Processes))
+
+	for _, selector := range options.Processes {
+		if selector.Name == "" {
+			return nil, errors.New("process selector name is required")
+		}
+		if names[selector.Name] {
+			return nil, fmt.Errorf("duplicate process selector %s", selector.Name)
+		}
+		names[selector.Name] = true
+
+		if selector.NamePattern == "" && selector.Cmdline == "" && selector.Pidfile == "" {
+			return nil, fmt.Errorf("process selector %s has no conditions", selector.Name)
+		}
+
+		compiled := compiledSelector{ProcessSelector: selector}
+		if selector.NamePattern != "" {
+			pattern, err := regexp.Compile(selector.NamePattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid name pattern of %s: %v", selector.Name, err)
+			}
+			compiled.namePattern = pattern
+		}
+		selectors = append(selectors, compiled)
+	}
+
+	return &ProcessCollector{
+		interval:  interval,
+		selectors: selectors,
+		lastCPU:   make(map[int32]processCPUSample),
+		now:       time.Now,
+		list:      listProcesses,
+		describe:  describeProcess,
+		stats:     readProcessStats,
+	}, nil
+}
+
+func (c *ProcessCollector) Name() string {
+	return ProcessCollectorName
+}
+
+func (c *ProcessCollector) Interval() time.Duration {
+	return c.interval
+}
+
+func (c *ProcessCollector) Collect(ctx context.Context) ([]models.Metrics, error) {
+	var all []processInfo
+	metrics := make([]models.Metrics, 0)
+	errs := make([]error, 0)
+	alive := make(map[int32]bool)
+
+	for _, selector := range c.selectors {
+		var matched []processInfo
+
+		if selector.Pidfile != "" {
+			info, err := c.fromPidfile(ctx, selector.Pidfile)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("process %s: %v", selector.Name, err))
+			} else if selector.matches(info) {
+				matched = append(matched, info)
+			}
+		} else {
+			if all == nil {
+				var err error
+				if all, err = c.list(ctx); err != nil {
+					return nil, fmt.Errorf("error listing processes: %v", err)
+				}
+			}
+			for _, info := range all {
+				if selector.matches(info) {
+					matched = append(matched, info)
+				}
+			}
+		}
+
+		selectorMetrics, selectorErrs := c.collectSelector(ctx, selector.Name, matched, alive)
+		metrics = append(metrics, selectorMetrics...)
+		errs = append(errs, selectorErrs...)
+	}
+
+	c.forgetExited(alive)
+	return metrics, errors.Join(errs...)
+}
+
+func (c *ProcessCollector) collectSelector(ctx context.Context, name string, matched []processInfo, alive map[int32]bool) ([]models.Metrics, []error) {
+	type matchedProcess struct {
+		pid   int32
+		stats processStats
+	}
+
+	processes := make([]matchedProcess, 0, len(matched))
+	errs := make([]error, 0)
+	for _, info := range matched {
+		stats, err := c.stats(ctx, info.pid)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("process %s (pid %d): %v", name, info.pid, err))
+			// часть показателей может быть недоступна без прав, процесс пропускается только если не прочитано ничего
+			if stats == (processStats{}) {
+				continue
+			}
+		}
+		processes = append(processes, matchedProcess{pid: info.pid, stats: stats})
+	}
+
+	sort.Slice(processes, func(i, j int) bool {
+		if processes[i].stats.createTime != processes[j].stats.createTime {
+			return processes[i].stats.createTime < processes[j].stats.createTime
+		}
+		return processes[i].pid < processes[j].pid
+	})
+
+	now := c.now()
+	metrics := []models.Metrics{NewGauge(labeledName("ProcessCount", name), float64(len(processes)))}
+	for i, proc := range processes {
+		alive[proc.pid] = true
+		label := name + "_" + strconv.Itoa(i)
+
+		uptime := 0.0
+		if proc.stats.createTime > 0 {
+			uptime = now.Sub(time.UnixMilli(proc.stats.createTime)).Seconds()
+		}
+
+		metrics = append(metrics,
+			NewGauge(labeledName("ProcessRSS", label), float64(proc.stats.rss)),
+			NewGauge(labeledName("ProcessOpenFDs", label), float64(proc.stats.openFDs)),
+			NewGauge(labeledName("ProcessThreads", label), float64(proc.stats.threads)),
+			NewGauge(labeledName("ProcessUptime", label), uptime),
+		)
+		if percent, ok := c.cpuPercent(proc.pid, proc.stats.cpuSeconds, now); ok {
+			metrics = append(metrics, NewGauge(labeledName("ProcessCPUPercent", label), percent))
+		}
+	}
+	return metrics, errs
+}
+
+// cpuPercent считает загрузку процессора процессом с прошлого опроса
+func (c *ProcessCollector) cpuPercent(pid int32, cpuSeconds float64, now time.Time) (float64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	last, exists := c.lastCPU[pid]
+	c.lastCPU[pid] = processCPUSample{cpuSeconds: cpuSeconds, at: now}
+	if !exists {
+		return 0, false
+	}
+
+	elapsed := now.Sub(last.at).Seconds()
+	if elapsed <= 0 || cpuSeconds < last.cpuSeconds {
+		return 0, false
+	}
+	return (cpuSeconds - last.cpuSeconds) / elapsed * 100, true
+}
+
+func (c *ProcessCollector) forgetExited(alive map[int32]bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for pid := range c.lastCPU {
+		if !alive[pid] {
+			delete(c.lastCPU, pid)
+		}
+	}
+}
+
+func (c *ProcessCollector) fromPidfile(ctx context.Context, pidfile string) (processInfo, error) {
+	data, err := os.ReadFile(pidfile)
+	if err != nil {
+		return processInfo{}, fmt.Errorf("error reading pidfile: %v", err)
+	}
+	pid, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 32)
+	if err != nil {
+		return processInfo{}, fmt.Errorf("invalid pidfile %s: %v", pidfile, err)
+	}
+	return c.describe(ctx, int32(pid))
+}
+
+func (selector compiledSelector) matches(info processInfo) bool {
+	if selector.namePattern != nil && !selector.namePattern.MatchString(info.name) {
+		return false
+	}
+	if selector.Cmdline != "" && !strings.Contains(info.cmdline, selector.Cmdline) {
+		return false
+	}
+	return true
+}
+
+func listProcesses(ctx context.Context) ([]processInfo, error) {
+	pids, err := process.PidsWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]processInfo, 0, len(pids))
+	for _, pid := range pids {
+		info, err := describeProcess(ctx, pid)
+		if err != nil {
+			// процесс мог завершиться во время обхода
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func describeProcess(ctx context.Context, pid int32) (processInfo, error) {
+	proc, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return processInfo{}, err
+	}
+	name, err := proc.NameWithContext(ctx)
+	if err != nil {
+		return processInfo{}, err
+	}
+	cmdline, _ := proc.CmdlineWithContext(ctx)
+
+	return processInfo{pid: pid, name: name, cmdline: cmdline}, nil
+}
+
+func readProcessStats(ctx context.Context, pid int32) (processStats, error) {
+	proc, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return processStats{}, err
+	}
+
+	stats := processStats{}
+	errs := make([]error, 0)
+
+	if stats.createTime, err = proc.CreateTimeWithContext(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if memory, err := proc.MemoryInfoWithContext(ctx); err != nil {
+		errs = append(errs, err)
+	} else {
+		stats.rss = memory.RSS
+	}
+	if times, err := proc.TimesWithContext(ctx); err != nil {
+		errs = append(errs, err)
+	} else {
+		stats.cpuSeconds = times.User + times.System
+	}
+	if stats.openFDs, err = proc.NumFDsWithContext(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if stats.threads, err = proc.NumThreadsWithContext(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	return stats, errors.Join(errs...)
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessCollector_Collect(t *testing.T) {
+	collector, err := NewProcessCollector(time.Second, ProcessOptions{
+		Processes: []ProcessSelector{
+			{Name: "api", NamePattern: "^api-server$", Cmdline: "--port"},
+		},
+	})
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	cpuSeconds := map[int32]float64{20: 1, 10: 5}
+
+	collector.now = func() time.Time { return now }
+	collector.list = func(_ context.Context) ([]processInfo, error) {
+		return []processInfo{
+			{pid: 20, name: "api-server", cmdline: "api-server --port 8081"},
+			{pid: 10, name: "api-server", cmdline: "api-server --port 8080"},
+			{pid: 30, name: "api-server", cmdline: "api-server migrate"},
+			{pid: 40, name: "postgres", cmdline: "postgres --port 5432"},
+		}, nil
+	}
+	collector.stats = func(_ context.Context, pid int32) (processStats, error) {
+		return processStats{
+			// процесс с pid 20 запущен раньше
+			createTime: map[int32]int64{20: 100_000, 10: 200_000}[pid],
+			rss:        uint64(pid) * 1024,
+			cpuSeconds: cpuSeconds[pid],
+			openFDs:    int32(pid),
+			threads:    4,
+		}, nil
+	}
+
+	first, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+
+	gauges := gaugeValues(first)
+	assert.Equal(t, 2.0, gauges["ProcessCount_api"])
+	assert.Equal(t, 20480.0, gauges["ProcessRSS_api_0"])
+	assert.Equal(t, 10240.0, gauges["ProcessRSS_api_1"])
+	assert.Equal(t, 20.0, gauges["ProcessOpenFDs_api_0"])
+	assert.Equal(t, 4.0, gauges["ProcessThreads_api_1"])
+	assert.Equal(t, 900.0, gauges["ProcessUptime_api_0"])
+	assert.NotContains(t, gauges, "ProcessCPUPercent_api_0")
+
+	now = now.Add(10 * time.Second)
+	cpuSeconds[20] = 6
+
+	second, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+
+	gauges = gaugeValues(second)
+	assert.InDelta(t, 50.0, gauges["ProcessCPUPercent_api_0"], 0.001)
+	assert.InDelta(t, 0.0, gauges["ProcessCPUPercent_api_1"], 0.001)
+}
+
+func TestProcessCollector_Pidfile(t *testing.T) {
+	pidfile := filepath.Join(t.TempDir(), "agent.pid")
+	require.NoError(t, os.WriteFile(pidfile, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644))
+
+	collector, err := NewProcessCollector(time.Second, ProcessOptions{
+		Processes: []ProcessSelector{{Name: "self", Pidfile: pidfile}},
+	})
+	require.NoError(t, err)
+
+	metrics, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+
+	gauges := gaugeValues(metrics)
+	assert.Equal(t, 1.0, gauges["ProcessCount_self"])
+	assert.Greater(t, gauges["ProcessRSS_self_0"], 0.0)
+	assert.Greater(t, gauges["ProcessThreads_self_0"], 0.0)
+}
+
+func TestNewProcessCollector_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector ProcessSelector
+	}{
+		{name: "without name", selector: ProcessSelector{NamePattern: "api"}},
+		{name: "without conditions", selector: ProcessSelector{Name: "api"}},
+		{name: "invalid regexp", selector: ProcessSelector{Name: "api", NamePattern: "("}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewProcessCollector(time.Second, ProcessOptions{Processes: []ProcessSelector{tt.selector}})
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+)
+
+const PrometheusCollectorName = "prometheus"
+
+const defaultScrapeTimeout = 5 * time.Second
+
+// ScrapeTarget адрес, с которого читаются метрики в формате Prometheus
+type ScrapeTarget struct {
+	// Name имя цели в метрике PrometheusUp, по умолчанию хост из URL
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url"`
+	// Timeout ограничение времени одного чтения
+	Timeout Duration `json:"timeout,omitempty"`
+	// Prefix добавляется к именам метрик цели
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// PrometheusOptions настройки коллектора Prometheus
+type PrometheusOptions struct {
+	Targets []ScrapeTarget `json:"targets"`
+}
+
+func init() {
+	RegisterCollector(PrometheusCollectorName, func(interval time.Duration, options json.RawMessage) (Collector, error) {
+		prometheusOptions := PrometheusOptions{}
+		if err := decodeOptions(options, &prometheusOptions); err != nil {
+			return nil, err
+		}
+		return NewPrometheusCollector(interval, prometheusOptions)
+	}, false)
+}
+
+// PrometheusCollector читает метрики локальных сервисов в текстовом формате Prometheus.
+// Цели опрашиваются параллельно, доступность каждой отправляется в PrometheusUp_<name>.
+type PrometheusCollector struct {
+	interval time.Duration
+	targets  []ScrapeTarget
+	client   *http.Client
+	counters *cumulativeCounters
+}
+
+func NewPrometheusCollector(interval time.Duration, options PrometheusOptions) (*PrometheusCollector, error) {
+	targets := make([]ScrapeTarget, 0, len(options.Targets))
+	names := make(map[string]bool, len(options.Targets))
+
+	for _, target := range options.Targets {
+		parsed, err := url.Parse(target.URL)
+		if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return nil, fmt.Errorf("invalid scrape url %q", target.URL)
+		}
+		if target.Name == "" {
+			target.Name = parsed.Host
+		}
+		if names[target.Name] {
+			return nil, fmt.Errorf("duplicate scrape target %s", target.Name)
+		}
+		names[target.Name] = true
+
+		if target.Timeout <= 0 {
+			target.Timeout = Duration(defaultScrapeTimeout)
+		}
+		targets = append(targets, target)
+	}
+
+	return &PrometheusCollector{
+		interval: interval,
+		targets:  targets,
+		client:   &http.Client{},
+		counters: newCumulativeCounters(),
+	}, nil
+}
+
+func (c *PrometheusCollector) Name() string {
+	return PrometheusCollectorName
+}
+
+func (c *PrometheusCollector) Interval() time.Duration {
+	return c.interval
+}
+
+func (c *PrometheusCollector) Collect(ctx context.Context) ([]models.Metrics, error) {
+	results := make([][]models.Metrics, len(c.targets))
+	errs := make([]error, len(c.targets))
+
+	var wg sync.WaitGroup
+	for i, target := range c.targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = c.scrape(ctx, target)
+		}()
+	}
+	wg.Wait()
+
+	metrics := make([]models.Metrics, 0)
+	for i, target := range c.targets {
+		up := 1.0
+		if errs[i] != nil {
+			up = 0
+		}
+		metrics = append(metrics, NewGauge(labeledName("PrometheusUp", target.Name), up))
+		metrics = append(metrics, results[i]...)
+	}
+	return metrics, errors.Join(errs...)
+}
+
+func (c *PrometheusCollector) scrape(ctx context.Context, target ScrapeTarget) ([]models.Metrics, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(target.Timeout))
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("scrape %s: %v", target.Name, err)
+	}
+	request.Header.Set("Accept", "text/plain;version=0.0.4")
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("scrape %s: %v", target.Name, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scrape %s: unexpected status %d", target.Name, response.StatusCode)
+	}
+
+	samples, err := parsePrometheusText(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("scrape %s: %v", target.Name, err)
+	}
+	return promMetrics(target.Prefix, samples, c.counters), nil
+}
@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const promExposition = `# HELP http_requests_total Total requests.
+# TYPE http_requests_total counter
+http_requests_total{method="get",code="200"} %d 1700000000000
+http_requests_total{method="post",code="500"} 3
+# TYPE queue_size gauge
+queue_size 12.5
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{le="0.1"} 5
+request_duration_seconds_bucket{le="+Inf"} %d
+request_duration_seconds_sum 1.5
+request_duration_seconds_count %d
+# TYPE rpc_latency_seconds summary
+rpc_latency_seconds{quantile="0.5"} 0.02
+rpc_latency_seconds_count 4
+temperature_celsius{sensor="cpu \"0\""} NaN
+up_time 42
+`
+
+func TestParsePrometheusText(t *testing.T) {
+	samples, err := parsePrometheusText(strings.NewReader(fmt.Sprintf(promExposition, 10, 7, 7)))
+	require.NoError(t, err)
+	require.Len(t, samples, 11)
+
+	assert.Equal(t, promSample{
+		name:   "http_requests_total",
+		labels: []promLabel{{name: "code", value: "200"}, {name: "method", value: "get"}},
+		value:  10,
+		kind:   promTypeCounter,
+	}, samples[0])
+	assert.Equal(t, promTypeHistogram, samples[3].kind)
+	assert.Equal(t, promTypeSummary, samples[8].kind)
+	assert.Equal(t, `cpu "0"`, samples[9].labels[0].value)
+	assert.Equal(t, promTypeUntyped, samples[10].kind)
+
+	_, err = parsePrometheusText(strings.NewReader("broken{label=\"x\" 1\n"))
+	assert.Error(t, err)
+	_, err = parsePrometheusText(strings.NewReader("metric abc\n"))
+	assert.Error(t, err)
+}
+
+func TestPrometheusCollector_Collect(t *testing.T) {
+	requests, histogramCount := 10, 7
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintf(w, promExposition, requests, histogramCount, histogramCount)
+	}))
+	defer server.Close()
+
+	collector, err := NewPrometheusCollector(time.Second, PrometheusOptions{
+		Targets: []ScrapeTarget{{Name: "app", URL: server.URL + "/metrics", Prefix: "app_"}},
+	})
+	require.NoError(t, err)
+
+	first, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+
+	gauges := gaugeValues(first)
+	assert.Equal(t, 1.0, gauges["PrometheusUp_app"])
+	assert.Equal(t, 12.5, gauges["app_queue_size"])
+	assert.Equal(t, 1.5, gauges["app_request_duration_seconds_sum"])
+	assert.Equal(t, 0.02, gauges["app_rpc_latency_seconds_quantile_0.5"])
+	assert.Equal(t, 42.0, gauges["app_up_time"])
+	assert.NotContains(t, gauges, "app_temperature_celsius_sensor_cpu__0")
+	for name := range gauges {
+		assert.NotContains(t, name, "bucket")
+	}
+	// первое чтение только запоминает значения счетчиков
+	assert.Empty(t, counterValues(first))
+
+	requests, histogramCount = 15, 9
+	second, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+
+	counters := counterValues(second)
+	assert.Equal(t, int64(5), counters["app_http_requests_total_code_200_method_get"])
+	assert.Equal(t, int64(0), counters["app_http_requests_total_code_500_method_post"])
+	assert.Equal(t, int64(2), counters["app_request_duration_seconds_count"])
+}
+
+func TestPrometheusCollector_TargetTimeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintln(w, "queue_size 1")
+	}))
+	defer fast.Close()
+
+	collector, err := NewPrometheusCollector(time.Second, PrometheusOptions{
+		Targets: []ScrapeTarget{
+			{Name: "slow", URL: slow.URL, Timeout: Duration(50 * time.Millisecond)},
+			{Name: "fast", URL: fast.URL},
+		},
+	})
+	require.NoError(t, err)
+
+	started := time.Now()
+	metrics, err := collector.Collect(context.Background())
+	assert.Error(t, err)
+	assert.Less(t, time.Since(started), 500*time.Millisecond)
+
+	gauges := gaugeValues(metrics)
+	assert.Equal(t, 0.0, gauges["PrometheusUp_slow"])
+	assert.Equal(t, 1.0, gauges["PrometheusUp_fast"])
+	assert.Equal(t, 1.0, gauges["queue_size"])
+}
+
+func TestNewPrometheusCollector_InvalidTargets(t *testing.T) {
+	_, err := NewPrometheusCollector(time.Second, PrometheusOptions{Targets: []ScrapeTarget{{URL: "localhost:9100"}}})
+	assert.Error(t, err)
+
+	_, err = NewPrometheusCollector(time.Second, PrometheusOptions{Targets: []ScrapeTarget{
+		{URL: "http://localhost:9100/metrics"},
+		{URL: "http://localhost:9100/other"},
+	}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,211 @@
+package agent
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+)
+
+const (
+	promTypeCounter   = "counter"
+	promTypeGauge     = "gauge"
+	promTypeHistogram = "histogram"
+	promTypeSummary   = "summary"
+	promTypeUntyped   = "untyped"
+)
+
+type promLabel struct {
+	name  string
+	value string
+}
+
+// promSample одно значение текстового формата Prometheus
+type promSample struct {
+	name   string
+	labels []promLabel
+	value  float64
+	// kind тип метрики из строки "# TYPE", для серий гистограмм и summary — тип семейства
+	kind string
+}
+
+// parsePrometheusText разбирает текстовый формат Prometheus (text exposition format 0.0.4).
+// Отметки времени у значений игнорируются.
+func parsePrometheusText(reader io.Reader) ([]promSample, error) {
+	types := make(map[string]string)
+	samples := make([]promSample, 0)
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			fields := strings.Fields(line)
+			if len(fields) >= 4 && fields[1] == "TYPE" {
+				types[fields[2]] = fields[3]
+			}
+			continue
+		}
+
+		sample, err := parsePromSample(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		sample.kind = promSampleType(types, sample.name)
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// promSampleType определяет тип значения по имени, учитывая суффиксы серий гистограмм и summary
+func promSampleType(types map[string]string, name string) string {
+	if kind, exists := types[name]; exists {
+		return kind
+	}
+	for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+		family, found := strings.CutSuffix(name, suffix)
+		if !found {
+			continue
+		}
+		if kind := types[family]; kind == promTypeHistogram || kind == promTypeSummary {
+			return kind
+		}
+	}
+	return promTypeUntyped
+}
+
+func parsePromSample(line string) (promSample, error) {
+	sample := promSample{}
+
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return sample, fmt.Errorf("invalid sample %q", line)
+	}
+	sample.name = line[:end]
+	rest := line[end:]
+
+	if rest[0] == '{' {
+		labels, tail, err := parsePromLabels(rest[1:])
+		if err != nil {
+			return sample, err
+		}
+		sample.labels = labels
+		rest = tail
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return sample, fmt.Errorf("invalid value of %s", sample.name)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return sample, fmt.Errorf("invalid value of %s: %v", sample.name, err)
+	}
+	sample.value = value
+	return sample, nil
+}
+
+// parsePromLabels разбирает метки после "{" и возвращает остаток строки после "}"
+func parsePromLabels(text string) ([]promLabel, string, error) {
+	labels := make([]promLabel, 0)
+	for {
+		text = strings.TrimLeft(text, " \t")
+		if strings.HasPrefix(text, "}") {
+			break
+		}
+
+		name, rest, found := strings.Cut(text, "=")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, "", fmt.Errorf("invalid labels %q", text)
+		}
+		rest = strings.TrimLeft(rest, " \t")
+		if !strings.HasPrefix(rest, `"`) {
+			return nil, "", fmt.Errorf("label %s is not quoted", name)
+		}
+
+		var value strings.Builder
+		i := 1
+		for ; i < len(rest) && rest[i] != '"'; i++ {
+			if rest[i] == '\\' && i+1 < len(rest) {
+				i++
+				switch rest[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(rest[i])
+				}
+				continue
+			}
+			value.WriteByte(rest[i])
+		}
+		if i == len(rest) {
+			return nil, "", fmt.Errorf("unterminated value of label %s", name)
+		}
+		labels = append(labels, promLabel{name: strings.TrimSpace(name), value: value.String()})
+
+		text = strings.TrimLeft(rest[i+1:], " \t")
+		text = strings.TrimPrefix(text, ",")
+	}
+
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].name < labels[j].name
+	})
+	return labels, text[1:], nil
+}
+
+// promMetricName составляет имя метрики из префикса, имени и значений меток,
+// например http_requests_total{code="200",method="get"} превращается в http_requests_total_code_200_method_get
+func promMetricName(prefix string, sample promSample) string {
+	name := sanitizeMetricName(prefix + sample.name)
+	for _, label := range sample.labels {
+		name = labeledName(labeledName(name, label.name), label.value)
+	}
+	return name
+}
+
+// promMetrics переводит значения Prometheus в метрики агента.
+// Накопительные значения (counter, _count гистограмм и summary) отправляются приростом с прошлого чтения,
+// gauge, untyped, _sum и квантили summary — как gauge. Бакеты гистограмм и нечисловые значения пропускаются.
+func promMetrics(prefix string, samples []promSample, counters *cumulativeCounters) []models.Metrics {
+	metrics := make([]models.Metrics, 0, len(samples))
+	for _, sample := range samples {
+		if math.IsNaN(sample.value) || math.IsInf(sample.value, 0) {
+			continue
+		}
+
+		name := promMetricName(prefix, sample)
+		cumulative := sample.kind == promTypeCounter
+		if sample.kind == promTypeHistogram || sample.kind == promTypeSummary {
+			if strings.HasSuffix(sample.name, "_bucket") {
+				continue
+			}
+			cumulative = strings.HasSuffix(sample.name, "_count")
+		}
+
+		if !cumulative {
+			metrics = append(metrics, NewGauge(name, sample.value))
+			continue
+		}
+		if sample.value < 0 {
+			continue
+		}
+		if delta, ok := counters.delta(name, uint64(sample.value)); ok {
+			metrics = append(metrics, NewCounter(name, delta))
+		}
+	}
+	return metrics
+}
@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Bessima/metrics-collect/internal/middlewares/compress"
+	models "github.com/Bessima/metrics-collect/internal/model"
+	"github.com/go-chi/chi/v5"
+)
+
+const PushCollectorName = "push"
+
+const (
+	defaultPushAddress = "127.0.0.1:9091"
+	maxPushBodySize    = 10 << 20
+)
+
+// PushOptions настройки локального HTTP-приема метрик
+type PushOptions struct {
+	// Address адрес HTTP-сервера, пустая строка отключает прием по TCP
+	Address string `json:"address"`
+	// UnixSocket путь к unix сокету
+	UnixSocket string `json:"unix_socket,omitempty"`
+}
+
+func defaultPushOptions() PushOptions {
+	return PushOptions{Address: defaultPushAddress}
+}
+
+func init() {
+	RegisterCollector(PushCollectorName, func(interval time.Duration, options json.RawMessage) (Collector, error) {
+		pushOptions := defaultPushOptions()
+		if err := decodeOptions(options, &pushOptions); err != nil {
+			return nil, err
+		}
+		return NewPushCollector(interval, pushOptions)
+	}, false)
+}
+
+// PushCollector принимает от приложений на хосте метрики в формате /updates/ сервера
+// и передает их в общую очередь отправки агента. Так приложения получают буферизацию,
+// подпись и повторные попытки, не зная адреса сервера и ключа.
+// Приросты счетчиков суммируются, для gauge отправляется последнее значение.
+type PushCollector struct {
+	interval time.Duration
+	options  PushOptions
+
+	mutex    sync.Mutex
+	counters map[string]int64
+	gauges   map[string]float64
+
+	server    *http.Server
+	listeners []net.Listener
+}
+
+func NewPushCollector(interval time.Duration, options PushOptions) (*PushCollector, error) {
+	if options.Address == "" && options.UnixSocket == "" {
+		return nil, errors.New("push address or unix socket is required")
+	}
+
+	collector := &PushCollector{
+		interval: interval,
+		options:  options,
+		counters: make(map[string]int64),
+		gauges:   make(map[string]float64),
+	}
+
+	router := chi.NewRouter()
+	router.Use(compress.GZIPMiddleware)
+	router.Post("/updates/", collector.updatesHandler)
+	collector.server = &http.Server{Handler: router, ReadHeaderTimeout: 5 * time.Second}
+
+	return collector, nil
+}
+
+func (c *PushCollector) Name() string {
+	return PushCollectorName
+}
+
+func (c *PushCollector) Interval() time.Duration {
+	return c.interval
+}
+
+// Start открывает сокеты и обслуживает запросы до отмены ctx
+func (c *PushCollector) Start(ctx context.Context) error {
+	if c.options.Address != "" {
+		listener, err := net.Listen("tcp", c.options.Address)
+		if err != nil {
+			return fmt.Errorf("error listening push endpoint on %s: %v", c.options.Address, err)
+		}
+		c.listeners = append(c.listeners, listener)
+	}
+
+	if c.options.UnixSocket != "" {
+		// сокет мог остаться от предыдущего запуска
+		if err := os.Remove(c.options.UnixSocket); err != nil && !errors.Is(err, os.ErrNotExist) {
+			c.closeListeners()
+			return fmt.Errorf("error removing stale socket %s: %v", c.options.UnixSocket, err)
+		}
+		listener, err := net.Listen("unix", c.options.UnixSocket)
+		if err != nil {
+			c.closeListeners()
+			return fmt.Errorf("error listening push endpoint on %s: %v", c.options.UnixSocket, err)
+		}
+		c.listeners = append(c.listeners, listener)
+	}
+
+	for _, listener := range c.listeners {
+		log.Printf("Push endpoint started on %s", listener.Addr())
+		go func() {
+			if err := c.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Push endpoint on %s stopped: %v", listener.Addr(), err)
+			}
+		}()
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := c.server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error stopping push endpoint: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (c *PushCollector) closeListeners() {
+	for _, listener := range c.listeners {
+		listener.Close()
+	}
+}
+
+func (c *PushCollector) updatesHandler(w http.ResponseWriter, r *http.Request) {
+	var metrics []models.Metrics
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPushBodySize)
+	if err := json.NewDecoder(r.Body).Decode(&metrics); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// пачка принимается целиком или не принимается совсем
+	for _, metric := range metrics {
+		if err := validateMetric(metric); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, metric := range metrics {
+		if metric.MType == models.Counter {
+			c.counters[metric.ID] += *metric.Delta
+		} else {
+			c.gauges[metric.ID] = *metric.Value
+		}
+	}
+}
+
+// Collect возвращает метрики, принятые с прошлого вызова
+func (c *PushCollector) Collect(_ context.Context) ([]models.Metrics, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	metrics := make([]models.Metrics, 0, len(c.counters)+len(c.gauges))
+	for _, name := range sortedKeys(c.counters) {
+		metrics = append(metrics, NewCounter(name, c.counters[name]))
+	}
+	for _, name := range sortedKeys(c.gauges) {
+		metrics = append(metrics, NewGauge(name, c.gauges[name]))
+	}
+
+	c.counters = make(map[string]int64)
+	c.gauges = make(map[string]float64)
+	return metrics, nil
+}
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushCollector_Updates(t *testing.T) {
+	collector, err := NewPushCollector(time.Second, defaultPushOptions())
+	require.NoError(t, err)
+
+	data, err := CompressJSONMetrics([]models.Metrics{
+		NewCounter("requests", 2),
+		NewCounter("requests", 3),
+		NewGauge("temperature", 20),
+		NewGauge("temperature", 21.5),
+	})
+	require.NoError(t, err)
+
+	request := httptest.NewRequest(http.MethodPost, "/updates/", data)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Content-Encoding", "gzip")
+	recorder := httptest.NewRecorder()
+	collector.server.Handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	metrics, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"requests": 5}, counterValues(metrics))
+	assert.Equal(t, map[string]float64{"temperature": 21.5}, gaugeValues(metrics))
+
+	metrics, err = collector.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, metrics)
+}
+
+func TestPushCollector_InvalidPayload(t *testing.T) {
+	collector, err := NewPushCollector(time.Second, defaultPushOptions())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "not json", body: "requests 1"},
+		{name: "counter without delta", body: `[{"id":"ok","type":"gauge","value":1},{"id":"requests","type":"counter"}]`},
+		{name: "unknown type", body: `[{"id":"requests","type":"histogram","value":1}]`},
+		{name: "empty id", body: `[{"type":"gauge","value":1}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader(tt.body))
+			recorder := httptest.NewRecorder()
+			collector.server.Handler.ServeHTTP(recorder, request)
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		})
+	}
+
+	// пачка с ошибкой не принимается частично
+	metrics, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, metrics)
+}
+
+func TestPushCollector_UnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "push.sock")
+	collector, err := NewPushCollector(time.Second, PushOptions{UnixSocket: socket})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, collector.Start(ctx))
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	response, err := client.Post("http://agent/updates/", "application/json",
+		bytes.NewBufferString(`[{"id":"jobs","type":"counter","delta":4}]`))
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	metrics, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"jobs": 4}, counterValues(metrics))
+}
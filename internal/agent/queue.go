@@ -0,0 +1,195 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// OverflowPolicy поведение очереди при переполнении
+type OverflowPolicy string
+
+const (
+	// OverflowBlock ждет, пока в очереди освободится место
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest удаляет самый старый элемент очереди
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+	// OverflowDropNewest отбрасывает добавляемый элемент
+	OverflowDropNewest OverflowPolicy = "drop_newest"
+)
+
+var ErrQueueClosed = errors.New("queue is closed")
+
+// ParseOverflowPolicy проверяет название политики переполнения
+func ParseOverflowPolicy(value string) (OverflowPolicy, error) {
+	switch policy := OverflowPolicy(value); policy {
+	case OverflowBlock, OverflowDropOldest, OverflowDropNewest:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown overflow policy %q", value)
+	}
+}
+
+// Queue ограниченная очередь между стадиями конвейера агента.
+// При переполнении очередь ждет или отбрасывает элементы согласно политике,
+// отброшенные элементы передаются в onDrop и учитываются в Dropped.
+type Queue[T any] struct {
+	name     string
+	capacity int
+	policy   OverflowPolicy
+	onDrop   func(T)
+
+	mutex sync.Mutex
+	// items кольцевой буфер: элементы с head, всего size
+	items    []T
+	head     int
+	size     int
+	closed   bool
+	dropped  uint64
+	notEmpty chan struct{}
+	notFull  chan struct{}
+}
+
+// NewQueue создает очередь. onDrop может быть nil.
+func NewQueue[T any](name string, capacity int, policy OverflowPolicy, onDrop func(T)) (*Queue[T], error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("queue %s: capacity must be positive", name)
+	}
+	if _, err := ParseOverflowPolicy(string(policy)); err != nil {
+		return nil, fmt.Errorf("queue %s: %v", name, err)
+	}
+
+	return &Queue[T]{
+		name:     name,
+		capacity: capacity,
+		policy:   policy,
+		onDrop:   onDrop,
+		items:    make([]T, capacity),
+		notEmpty: make(chan struct{}, 1),
+		notFull:  make(chan struct{}, 1),
+	}, nil
+}
+
+func (q *Queue[T]) Name() string {
+	return q.name
+}
+
+// Push добавляет элемент. С политикой block ждет места до отмены ctx.
+func (q *Queue[T]) Push(ctx context.Context, item T) error {
+	for {
+		q.mutex.Lock()
+		if q.closed {
+			q.mutex.Unlock()
+			signal(q.notFull)
+			return ErrQueueClosed
+		}
+
+		if q.size < q.capacity {
+			q.items[(q.head+q.size)%q.capacity] = item
+			q.size++
+			q.mutex.Unlock()
+			signal(q.notEmpty)
+			return nil
+		}
+
+		switch q.policy {
+		case OverflowDropNewest:
+			q.dropped++
+			q.mutex.Unlock()
+			q.drop(item)
+			return nil
+		case OverflowDropOldest:
+			oldest := q.items[q.head]
+			q.items[q.head] = item
+			q.head = (q.head + 1) % q.capacity
+			q.dropped++
+			q.mutex.Unlock()
+			signal(q.notEmpty)
+			q.drop(oldest)
+			return nil
+		}
+		q.mutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-q.notFull:
+		}
+	}
+}
+
+// Pop возвращает самый старый элемент, ожидая его появления до отмены ctx или закрытия очереди
+func (q *Queue[T]) Pop(ctx context.Context) (T, error) {
+	for {
+		q.mutex.Lock()
+		if q.size > 0 {
+			var zero T
+			item := q.items[q.head]
+			q.items[q.head] = zero
+			q.head = (q.head + 1) % q.capacity
+			q.size--
+			hasMore := q.size > 0
+			q.mutex.Unlock()
+
+			signal(q.notFull)
+			if hasMore {
+				signal(q.notEmpty)
+			}
+			return item, nil
+		}
+		closed := q.closed
+		q.mutex.Unlock()
+
+		var zero T
+		if closed {
+			// будим следующего ожидающего, чтобы он тоже увидел закрытие
+			signal(q.notEmpty)
+			return zero, ErrQueueClosed
+		}
+
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case <-q.notEmpty:
+		}
+	}
+}
+
+// Close закрывает очередь: новые элементы не принимаются, оставшиеся можно дочитать через Pop
+func (q *Queue[T]) Close() {
+	q.mutex.Lock()
+	q.closed = true
+	q.mutex.Unlock()
+
+	signal(q.notEmpty)
+	signal(q.notFull)
+}
+
+// Len текущая глубина очереди
+func (q *Queue[T]) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.size
+}
+
+// Dropped количество отброшенных элементов с момента создания очереди
+func (q *Queue[T]) Dropped() uint64 {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.dropped
+}
+
+func (q *Queue[T]) drop(item T) {
+	if q.onDrop != nil {
+		q.onDrop(item)
+	}
+}
+
+// signal будит одного ожидающего, не блокируясь, если сигнал уже отправлен
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue_DropOldest(t *testing.T) {
+	dropped := make([]int, 0)
+	queue, err := NewQueue("test", 2, OverflowDropOldest, func(item int) { dropped = append(dropped, item) })
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for i := 1; i <= 4; i++ {
+		require.NoError(t, queue.Push(ctx, i))
+	}
+
+	assert.Equal(t, 2, queue.Len())
+	assert.Equal(t, uint64(2), queue.Dropped())
+	assert.Equal(t, []int{1, 2}, dropped)
+
+	item, err := queue.Pop(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, item)
+	item, err = queue.Pop(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, item)
+}
+
+func TestQueue_DropNewest(t *testing.T) {
+	dropped := make([]int, 0)
+	queue, err := NewQueue("test", 2, OverflowDropNewest, func(item int) { dropped = append(dropped, item) })
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for i := 1; i <= 4; i++ {
+		require.NoError(t, queue.Push(ctx, i))
+	}
+
+	assert.Equal(t, uint64(2), queue.Dropped())
+	assert.Equal(t, []int{3, 4}, dropped)
+
+	item, err := queue.Pop(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, item)
+}
+
+func TestQueue_Block(t *testing.T) {
+	queue, err := NewQueue[int]("test", 1, OverflowBlock, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, queue.Push(context.Background(), 1))
+
+	// очередь заполнена: добавление ждет до отмены контекста
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, queue.Push(ctx, 2), context.DeadlineExceeded)
+
+	pushed := make(chan error)
+	go func() {
+		pushed <- queue.Push(context.Background(), 3)
+	}()
+
+	item, err := queue.Pop(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, item)
+	require.NoError(t, <-pushed)
+
+	item, err = queue.Pop(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, item)
+	assert.Equal(t, uint64(0), queue.Dropped())
+}
+
+func TestQueue_Close(t *testing.T) {
+	queue, err := NewQueue[int]("test", 2, OverflowBlock, nil)
+	require.NoError(t, err)
+	require.NoError(t, queue.Push(context.Background(), 1))
+
+	waiting := make(chan error, 2)
+	empty, err := NewQueue[int]("empty", 1, OverflowBlock, nil)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := empty.Pop(context.Background())
+			waiting <- err
+		}()
+	}
+	empty.Close()
+	assert.ErrorIs(t, <-waiting, ErrQueueClosed)
+	assert.ErrorIs(t, <-waiting, ErrQueueClosed)
+
+	queue.Close()
+	assert.ErrorIs(t, queue.Push(context.Background(), 2), ErrQueueClosed)
+
+	// оставшиеся элементы дочитываются после закрытия
+	item, err := queue.Pop(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, item)
+	_, err = queue.Pop(context.Background())
+	assert.ErrorIs(t, err, ErrQueueClosed)
+}
+
+func TestNewQueue_Invalid(t *testing.T) {
+	_, err := NewQueue[int]("test", 0, OverflowBlock, nil)
+	assert.Error(t, err)
+
+	_, err = NewQueue[int]("test", 1, OverflowPolicy("random"), nil)
+	assert.Error(t, err)
+}
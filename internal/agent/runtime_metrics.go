@@ -0,0 +1,279 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+)
+
+const RuntimeCollectorName = "runtime"
+
+const runtimeMetricPrefix = "Runtime"
+
+// runtimeQuantiles квантили, в которые разворачиваются гистограммы runtime/metrics
+var runtimeQuantiles = []struct {
+	suffix   string
+	quantile float64
+}{
+	{"P50", 0.5},
+	{"P90", 0.9},
+	{"P99", 0.99},
+}
+
+// RuntimeOptions настройки коллектора runtime/metrics
+type RuntimeOptions struct {
+	// MemStatsCompat дополнительно отправляет метрики под именами полей runtime.MemStats
+	MemStatsCompat bool `json:"memstats_compat"`
+}
+
+func init() {
+	RegisterCollector(RuntimeCollectorName, func(interval time.Duration, options json.RawMessage) (Collector, error) {
+		runtimeOptions := RuntimeOptions{}
+		if err := decodeOptions(options, &runtimeOptions); err != nil {
+			return nil, err
+		}
+		return NewRuntimeCollector(interval, runtimeOptions), nil
+	}, false)
+}
+
+// RuntimeCollector собирает метрики пакета runtime/metrics. В отличие от runtime.ReadMemStats
+// чтение не останавливает программу, а набор метрик определяется версией Go автоматически.
+// Накопительные целые значения отправляются счетчиками, гистограммы — квантилями за интервал опроса.
+type RuntimeCollector struct {
+	interval time.Duration
+	options  RuntimeOptions
+
+	descriptions map[string]metrics.Description
+	names        map[string]string
+
+	mutex      sync.Mutex
+	samples    []metrics.Sample
+	counters   *cumulativeCounters
+	histograms map[string][]uint64
+}
+
+func NewRuntimeCollector(interval time.Duration, options RuntimeOptions) *RuntimeCollector {
+	all := metrics.All()
+
+	collector := &RuntimeCollector{
+		interval:     interval,
+		options:      options,
+		descriptions: make(map[string]metrics.Description, len(all)),
+		names:        make(map[string]string, len(all)),
+		samples:      make([]metrics.Sample, 0, len(all)),
+		counters:     newCumulativeCounters(),
+		histograms:   make(map[string][]uint64),
+	}
+	for _, description := range all {
+		if description.Kind == metrics.KindBad {
+			continue
+		}
+		collector.descriptions[description.Name] = description
+		collector.names[description.Name] = runtimeMetricName(description.Name)
+		collector.samples = append(collector.samples, metrics.Sample{Name: description.Name})
+	}
+	return collector
+}
+
+func (c *RuntimeCollector) Name() string {
+	return RuntimeCollectorName
+}
+
+func (c *RuntimeCollector) Interval() time.Duration {
+	return c.interval
+}
+
+func (c *RuntimeCollector) Collect(_ context.Context) ([]models.Metrics, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	metrics.Read(c.samples)
+
+	result := make([]models.Metrics, 0, len(c.samples))
+	for _, sample := range c.samples {
+		description := c.descriptions[sample.Name]
+		name := c.names[sample.Name]
+
+		switch sample.Value.Kind() {
+		case metrics.KindUint64:
+			value := sample.Value.Uint64()
+			if !description.Cumulative {
+				result = append(result, NewGauge(name, float64(value)))
+			} else if delta, ok := c.counters.delta(name, value); ok {
+				result = append(result, NewCounter(name, delta))
+			}
+		case metrics.KindFloat64:
+			// счетчики сервера целочисленные, поэтому дробные значения, в том числе накопительные, отправляются как gauge
+			result = append(result, NewGauge(name, sample.Value.Float64()))
+		case metrics.KindFloat64Histogram:
+			result = append(result, c.histogramQuantiles(name, sample.Value.Float64Histogram(), description.Cumulative)...)
+		}
+	}
+
+	if c.options.MemStatsCompat {
+		compat, err := gaugesFromMap(memStatsFromSamples(c.samples))
+		if err != nil {
+			return result, fmt.Errorf("error converting memstats compatible metrics: %v", err)
+		}
+		result = append(result, compat...)
+	}
+	return result, nil
+}
+
+// histogramQuantiles переводит гистограмму в квантили. Для накопительной гистограммы
+// учитываются только наблюдения с прошлого опроса (при первом опросе — с запуска программы),
+// если их не было — метрики не отправляются.
+func (c *RuntimeCollector) histogramQuantiles(name string, histogram *metrics.Float64Histogram, cumulative bool) []models.Metrics {
+	counts := histogram.Counts
+	if cumulative {
+		prev := c.histograms[name]
+		c.histograms[name] = append([]uint64(nil), histogram.Counts...)
+
+		if len(prev) == len(counts) {
+			delta := make([]uint64, len(counts))
+			for i := range counts {
+				if counts[i] >= prev[i] {
+					delta[i] = counts[i] - prev[i]
+				}
+			}
+			counts = delta
+		}
+	}
+
+	result := make([]models.Metrics, 0, len(runtimeQuantiles))
+	for _, q := range runtimeQuantiles {
+		value, ok := histogramQuantile(histogram.Buckets, counts, q.quantile)
+		if !ok {
+			return nil
+		}
+		result = append(result, NewGauge(name+q.suffix, value))
+	}
+	return result
+}
+
+// histogramQuantile оценивает квантиль по гистограмме верхней границей бакета, в который он попадает.
+// Для бакета без верхней границы используется нижняя.
+func histogramQuantile(buckets []float64, counts []uint64, quantile float64) (float64, bool) {
+	var total uint64
+	for _, count := range counts {
+		total += count
+	}
+	if total == 0 || len(buckets) != len(counts)+1 {
+		return 0, false
+	}
+
+	rank := uint64(math.Ceil(quantile * float64(total)))
+	if rank == 0 {
+		rank = 1
+	}
+
+	var seen uint64
+	for i, count := range counts {
+		seen += count
+		if seen < rank {
+			continue
+		}
+		if upper := buckets[i+1]; !math.IsInf(upper, 1) {
+			return upper, true
+		}
+		if lower := buckets[i]; !math.IsInf(lower, -1) {
+			return lower, true
+		}
+		return 0, false
+	}
+	return 0, false
+}
+
+// runtimeMetricName переводит имя вида "/gc/heap/allocs:bytes" в "RuntimeGcHeapAllocsBytes"
+func runtimeMetricName(name string) string {
+	var builder strings.Builder
+	builder.Grow(len(runtimeMetricPrefix) + len(name))
+	builder.WriteString(runtimeMetricPrefix)
+
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// memStatsFromSamples восстанавливает поля runtime.MemStats из runtime/metrics,
+// чтобы после перехода на коллектор runtime продолжали работать существующие графики.
+func memStatsFromSamples(samples []metrics.Sample) map[string]any {
+	values := make(map[string]uint64, len(samples))
+	floats := make(map[string]float64)
+	for _, sample := range samples {
+		switch sample.Value.Kind() {
+		case metrics.KindUint64:
+			values[sample.Name] = sample.Value.Uint64()
+		case metrics.KindFloat64:
+			floats[sample.Name] = sample.Value.Float64()
+		}
+	}
+
+	var gcStats debug.GCStats
+	debug.ReadGCStats(&gcStats)
+
+	var lastGC uint64
+	if !gcStats.LastGC.IsZero() {
+		lastGC = uint64(gcStats.LastGC.UnixNano())
+	}
+
+	gcCPUFraction := 0.0
+	if total := floats["/cpu/classes/total:cpu-seconds"]; total > 0 {
+		gcCPUFraction = floats["/cpu/classes/gc/total:cpu-seconds"] / total
+	}
+
+	heapObjects := values["/memory/classes/heap/objects:bytes"]
+	heapUnused := values["/memory/classes/heap/unused:bytes"]
+	heapFree := values["/memory/classes/heap/free:bytes"]
+	heapReleased := values["/memory/classes/heap/released:bytes"]
+	stacks := values["/memory/classes/heap/stacks:bytes"]
+
+	return map[string]any{
+		"Alloc":         heapObjects,
+		"BuckHashSys":   values["/memory/classes/profiling/buckets:bytes"],
+		"Frees":         values["/gc/heap/frees:objects"],
+		"GCCPUFraction": gcCPUFraction,
+		"GCSys":         values["/memory/classes/metadata/other:bytes"],
+		"HeapAlloc":     heapObjects,
+		"HeapIdle":      heapFree + heapReleased,
+		"HeapInuse":     heapObjects + heapUnused,
+		"HeapObjects":   values["/gc/heap/objects:objects"],
+		"HeapReleased":  heapReleased,
+		"HeapSys":       heapObjects + heapUnused + heapFree + heapReleased,
+		"LastGC":        lastGC,
+		"Lookups":       uint64(0),
+		"MCacheInuse":   values["/memory/classes/metadata/mcache/inuse:bytes"],
+		"MCacheSys":     values["/memory/classes/metadata/mcache/inuse:bytes"] + values["/memory/classes/metadata/mcache/free:bytes"],
+		"MSpanInuse":    values["/memory/classes/metadata/mspan/inuse:bytes"],
+		"MSpanSys":      values["/memory/classes/metadata/mspan/inuse:bytes"] + values["/memory/classes/metadata/mspan/free:bytes"],
+		"Mallocs":       values["/gc/heap/allocs:objects"],
+		"NextGC":        values["/gc/heap/goal:bytes"],
+		"NumForcedGC":   values["/gc/cycles/forced:gc-cycles"],
+		"NumGC":         values["/gc/cycles/total:gc-cycles"],
+		"OtherSys":      values["/memory/classes/other:bytes"],
+		"PauseTotalNs":  uint64(gcStats.PauseTotal.Nanoseconds()),
+		"StackInuse":    stacks,
+		"StackSys":      stacks + values["/memory/classes/os-stacks:bytes"],
+		"Sys":           values["/memory/classes/total:bytes"],
+		"TotalAlloc":    values["/gc/heap/allocs:bytes"],
+	}
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"testing"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var runtimeSink [][]byte
+
+func BenchmarkRuntimeCollector_Collect(b *testing.B) {
+	collector := NewRuntimeCollector(time.Second, RuntimeOptions{})
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := collector.Collect(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRuntimeCollector_Collect(t *testing.T) {
+	collector := NewRuntimeCollector(time.Second, RuntimeOptions{})
+
+	first, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+
+	gauges := gaugeValues(first)
+	assert.Contains(t, gauges, "RuntimeGcHeapGoalBytes")
+	assert.Contains(t, gauges, "RuntimeMemoryClassesTotalBytes")
+	// первый опрос только запоминает накопительные значения
+	assert.NotContains(t, counterValues(first), "RuntimeGcHeapAllocsBytes")
+
+	for i := 0; i < 100; i++ {
+		runtimeSink = append(runtimeSink, make([]byte, 1024))
+	}
+	runtime.GC()
+	runtimeSink = nil
+
+	second, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+
+	counters := counterValues(second)
+	assert.Greater(t, counters["RuntimeGcHeapAllocsBytes"], int64(0))
+	assert.GreaterOrEqual(t, counters["RuntimeGcCyclesTotalGcCycles"], int64(1))
+
+	gauges = gaugeValues(second)
+	assert.Contains(t, gauges, "RuntimeSchedPausesTotalGcSecondsP99")
+	assert.LessOrEqual(t, gauges["RuntimeSchedPausesTotalGcSecondsP50"], gauges["RuntimeSchedPausesTotalGcSecondsP99"])
+	assert.NotContains(t, gauges, "Alloc")
+}
+
+func TestRuntimeCollector_MemStatsCompat(t *testing.T) {
+	collector := NewRuntimeCollector(time.Second, RuntimeOptions{MemStatsCompat: true})
+
+	metrics, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+
+	gauges := gaugeValues(metrics)
+	for name := range GetAllMemStats() {
+		assert.Contains(t, gauges, name)
+	}
+	assert.Greater(t, gauges["HeapAlloc"], 0.0)
+	assert.Greater(t, gauges["Sys"], 0.0)
+	for _, metric := range metrics {
+		if _, exists := GetAllMemStats()[metric.ID]; exists {
+			assert.Equal(t, models.Gauge, metric.MType)
+		}
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	buckets := []float64{math.Inf(-1), 1, 2, 4, math.Inf(1)}
+
+	tests := []struct {
+		name     string
+		counts   []uint64
+		quantile float64
+		expected float64
+		ok       bool
+	}{
+		{name: "median", counts: []uint64{0, 5, 4, 1}, quantile: 0.5, expected: 2, ok: true},
+		{name: "p90", counts: []uint64{0, 5, 4, 1}, quantile: 0.9, expected: 4, ok: true},
+		{name: "first bucket", counts: []uint64{3, 0, 0, 0}, quantile: 0.5, expected: 1, ok: true},
+		{name: "last bucket", counts: []uint64{0, 0, 0, 2}, quantile: 0.99, expected: 4, ok: true},
+		{name: "empty", counts: []uint64{0, 0, 0, 0}, quantile: 0.5, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, ok := histogramQuantile(buckets, tt.counts, tt.quantile)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+}
+
+func TestRuntimeMetricName(t *testing.T) {
+	assert.Equal(t, "RuntimeGcHeapAllocsBytes", runtimeMetricName("/gc/heap/allocs:bytes"))
+	assert.Equal(t, "RuntimeCpuClassesGcMarkAssistCpuSeconds", runtimeMetricName("/cpu/classes/gc/mark/assist:cpu-seconds"))
+}
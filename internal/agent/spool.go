@@ -0,0 +1,169 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const spoolFileExt = ".batch"
+
+var ErrBatchTooLarge = errors.New("batch is larger than spool max size")
+
+// Spool хранит неотправленные пачки метрик на диске до восстановления связи с сервером.
+// Размер каталога ограничен maxSize, при переполнении удаляются самые старые пачки.
+type Spool struct {
+	dir     string
+	maxSize int64
+
+	mutex     sync.Mutex
+	replaying sync.Mutex
+	seq       uint64
+}
+
+type spoolFile struct {
+	path    string
+	size    int64
+	batchID string
+}
+
+func NewSpool(dir string, maxSize int64) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spool dir %s: %v", dir, err)
+	}
+	return &Spool{dir: dir, maxSize: maxSize}, nil
+}
+
+// Push сохраняет сжатую пачку метрик в конец очереди.
+// Идентификатор пачки сохраняется вместе с ней, чтобы сервер мог распознать повторную доставку.
+func (s *Spool) Push(batchID string, data []byte) error {
+	size := int64(len(data))
+	if s.maxSize > 0 && size > s.maxSize {
+		return ErrBatchTooLarge
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+
+	if s.maxSize > 0 {
+		total := size
+		for _, file := range files {
+			total += file.size
+		}
+		for len(files) > 0 && total > s.maxSize {
+			if err := os.Remove(files[0].path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to drop oldest batch %s: %v", files[0].path, err)
+			}
+			log.Printf("Spool is full, oldest batch %s was dropped", filepath.Base(files[0].path))
+			total -= files[0].size
+			files = files[1:]
+		}
+	}
+
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d_%s%s", time.Now().UnixNano(), s.seq%1000000, batchID, spoolFileExt)
+	tmpPath := filepath.Join(s.dir, name+".tmp")
+
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write batch to spool: %v", err)
+	}
+	return os.Rename(tmpPath, filepath.Join(s.dir, name))
+}
+
+// Replay отправляет сохраненные пачки в порядке их записи, включая пачки,
+// добавленные во время отправки, пока очередь не опустеет.
+// Отправка останавливается на первой ошибке, неотправленные пачки остаются в очереди.
+// Пачка, которую сервер отклонил (ErrBatchRejected), удаляется, чтобы не задерживать следующие.
+// Если повторная отправка уже выполняется в другой горутине, метод сразу возвращает 0.
+func (s *Spool) Replay(send func(batchID string, data []byte) error) (int, error) {
+	if !s.replaying.TryLock() {
+		return 0, nil
+	}
+	defer s.replaying.Unlock()
+
+	sent := 0
+	for {
+		s.mutex.Lock()
+		files, err := s.files()
+		s.mutex.Unlock()
+		if err != nil || len(files) == 0 {
+			return sent, err
+		}
+
+		for _, file := range files {
+			data, err := os.ReadFile(file.path)
+			if err != nil {
+				if os.IsNotExist(err) {
+					// пачка была вытеснена во время отправки
+					continue
+				}
+				return sent, err
+			}
+
+			err = send(file.batchID, data)
+			switch {
+			case errors.Is(err, ErrBatchRejected):
+				log.Printf("Spooled batch %s was rejected and dropped: %v", filepath.Base(file.path), err)
+			case err != nil:
+				return sent, err
+			default:
+				sent++
+			}
+
+			if err = os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+				return sent, err
+			}
+		}
+	}
+}
+
+// Len возвращает количество пачек, ожидающих отправки
+func (s *Spool) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	files, err := s.files()
+	if err != nil {
+		return 0
+	}
+	return len(files)
+}
+
+func (s *Spool) files() ([]spoolFile, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool dir %s: %v", s.dir, err)
+	}
+
+	files := make([]spoolFile, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), spoolFileExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		batchID := ""
+		if _, suffix, found := strings.Cut(strings.TrimSuffix(entry.Name(), spoolFileExt), "_"); found {
+			batchID = suffix
+		}
+		files = append(files, spoolFile{path: filepath.Join(s.dir, entry.Name()), size: info.Size(), batchID: batchID})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].path < files[j].path
+	})
+	return files, nil
+}
@@ -0,0 +1,159 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpool_ReplayInOrder(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	for _, batch := range []string{"first", "second", "third"} {
+		require.NoError(t, spool.Push("", []byte(batch)))
+	}
+	assert.Equal(t, 3, spool.Len())
+
+	var sent []string
+	count, err := spool.Replay(func(_ string, data []byte) error {
+		sent = append(sent, string(data))
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, []string{"first", "second", "third"}, sent)
+	assert.Equal(t, 0, spool.Len())
+}
+
+func TestSpool_ReplayStopsOnError(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	require.NoError(t, spool.Push("", []byte("first")))
+	require.NoError(t, spool.Push("", []byte("second")))
+
+	count, err := spool.Replay(func(_ string, data []byte) error {
+		if string(data) == "second" {
+			return errors.New("server unavailable")
+		}
+		return nil
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, 1, spool.Len())
+}
+
+func TestSpool_ReplaySendsBatchesPushedDuringReplay(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	require.NoError(t, spool.Push("", []byte("old")))
+
+	var sent []string
+	count, err := spool.Replay(func(_ string, data []byte) error {
+		if string(data) == "old" {
+			// новая пачка поставлена в спул, пока отправляется старая
+			require.NoError(t, spool.Push("", []byte("new")))
+		}
+		sent = append(sent, string(data))
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []string{"old", "new"}, sent)
+	assert.Equal(t, 0, spool.Len())
+}
+
+func TestSpool_ReplayDropsRejectedBatch(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	for _, batch := range []string{"rejected", "second"} {
+		require.NoError(t, spool.Push("", []byte(batch)))
+	}
+
+	var sent []string
+	count, err := spool.Replay(func(_ string, data []byte) error {
+		if string(data) == "rejected" {
+			return fmt.Errorf("%w: server returned status: 400", ErrBatchRejected)
+		}
+		sent = append(sent, string(data))
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []string{"second"}, sent)
+	assert.Equal(t, 0, spool.Len())
+}
+
+func TestSpool_DropOldest(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 10)
+	require.NoError(t, err)
+
+	require.NoError(t, spool.Push("", []byte("aaaa")))
+	require.NoError(t, spool.Push("", []byte("bbbb")))
+	require.NoError(t, spool.Push("", []byte("cccc")))
+
+	var sent []string
+	_, err = spool.Replay(func(_ string, data []byte) error {
+		sent = append(sent, string(data))
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bbbb", "cccc"}, sent)
+}
+
+func TestSpool_BatchTooLarge(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 4)
+	require.NoError(t, err)
+
+	err = spool.Push("", []byte("too large batch"))
+	assert.ErrorIs(t, err, ErrBatchTooLarge)
+	assert.Equal(t, 0, spool.Len())
+}
+
+func TestSpool_KeepsBatchID(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	require.NoError(t, spool.Push("4b8f0c2e-8a51-4e0f-9d3c-1f2a3b4c5d6e", []byte("batch")))
+
+	var ids []string
+	_, err = spool.Replay(func(batchID string, _ []byte) error {
+		ids = append(ids, batchID)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4b8f0c2e-8a51-4e0f-9d3c-1f2a3b4c5d6e"}, ids)
+}
+
+func TestSpool_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	spool, err := NewSpool(dir, 0)
+	require.NoError(t, err)
+	require.NoError(t, spool.Push("", []byte("before restart")))
+
+	restarted, err := NewSpool(dir, 0)
+	require.NoError(t, err)
+
+	var sent []string
+	count, err := restarted.Replay(func(_ string, data []byte) error {
+		sent = append(sent, string(data))
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []string{"before restart"}, sent)
+}
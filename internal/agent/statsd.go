@@ -0,0 +1,351 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+)
+
+const StatsDCollectorName = "statsd"
+
+const (
+	defaultStatsDAddress = ":8125"
+	statsDMaxPacketSize  = 65535
+)
+
+// statsDQuantiles квантили, которые отправляются для таймеров
+var statsDQuantiles = []struct {
+	suffix   string
+	quantile float64
+}{
+	{".p50", 0.5},
+	{".p90", 0.9},
+	{".p99", 0.99},
+}
+
+// StatsDOptions настройки приема метрик StatsD
+type StatsDOptions struct {
+	// Address UDP-адрес, пустая строка отключает прием по UDP
+	Address string `json:"address"`
+	// UnixSocket путь к unix datagram сокету
+	UnixSocket string `json:"unix_socket,omitempty"`
+}
+
+func defaultStatsDOptions() StatsDOptions {
+	return StatsDOptions{Address: defaultStatsDAddress}
+}
+
+func init() {
+	RegisterCollector(StatsDCollectorName, func(interval time.Duration, options json.RawMessage) (Collector, error) {
+		statsDOptions := defaultStatsDOptions()
+		if err := decodeOptions(options, &statsDOptions); err != nil {
+			return nil, err
+		}
+		return NewStatsDCollector(interval, statsDOptions)
+	}, false)
+}
+
+// statsDSample одно значение протокола StatsD: "name:value|type|@rate"
+type statsDSample struct {
+	name       string
+	value      float64
+	raw        string
+	metricType string
+	rate       float64
+	relative   bool
+}
+
+type statsDTimer struct {
+	values []float64
+	count  float64
+}
+
+// StatsDCollector принимает метрики в формате StatsD и агрегирует их между вызовами Collect.
+// Счетчики (c) суммируются с учетом частоты выборки, для датчиков (g) отправляется последнее значение,
+// для таймеров (ms, h) — количество, минимум, максимум, среднее и квантили, для множеств (s) — число уникальных значений.
+type StatsDCollector struct {
+	interval time.Duration
+	options  StatsDOptions
+
+	mutex    sync.Mutex
+	counters map[string]float64
+	gauges   map[string]float64
+	updated  map[string]bool
+	timers   map[string]*statsDTimer
+	sets     map[string]map[string]struct{}
+	invalid  int
+	lastErr  error
+
+	conns []net.PacketConn
+}
+
+func NewStatsDCollector(interval time.Duration, options StatsDOptions) (*StatsDCollector, error) {
+	if options.Address == "" && options.UnixSocket == "" {
+		return nil, errors.New("statsd address or unix socket is required")
+	}
+
+	return &StatsDCollector{
+		interval: interval,
+		options:  options,
+		counters: make(map[string]float64),
+		gauges:   make(map[string]float64),
+		updated:  make(map[string]bool),
+		timers:   make(map[string]*statsDTimer),
+		sets:     make(map[string]map[string]struct{}),
+	}, nil
+}
+
+func (c *StatsDCollector) Name() string {
+	return StatsDCollectorName
+}
+
+func (c *StatsDCollector) Interval() time.Duration {
+	return c.interval
+}
+
+// Start открывает сокеты и принимает пакеты до отмены ctx
+func (c *StatsDCollector) Start(ctx context.Context) error {
+	if c.options.Address != "" {
+		conn, err := net.ListenPacket("udp", c.options.Address)
+		if err != nil {
+			return fmt.Errorf("error listening statsd on %s: %v", c.options.Address, err)
+		}
+		c.conns = append(c.conns, conn)
+	}
+
+	if c.options.UnixSocket != "" {
+		// сокет мог остаться от предыдущего запуска
+		if err := os.Remove(c.options.UnixSocket); err != nil && !errors.Is(err, os.ErrNotExist) {
+			c.close()
+			return fmt.Errorf("error removing stale socket %s: %v", c.options.UnixSocket, err)
+		}
+		conn, err := net.ListenPacket("unixgram", c.options.UnixSocket)
+		if err != nil {
+			c.close()
+			return fmt.Errorf("error listening statsd on %s: %v", c.options.UnixSocket, err)
+		}
+		c.conns = append(c.conns, conn)
+	}
+
+	for _, conn := range c.conns {
+		log.Printf("StatsD listener started on %s", conn.LocalAddr())
+		go c.serve(conn)
+	}
+
+	go func() {
+		<-ctx.Done()
+		c.close()
+	}()
+	return nil
+}
+
+func (c *StatsDCollector) close() {
+	for _, conn := range c.conns {
+		conn.Close()
+	}
+	if c.options.UnixSocket != "" {
+		os.Remove(c.options.UnixSocket)
+	}
+}
+
+func (c *StatsDCollector) serve(conn net.PacketConn) {
+	buffer := make([]byte, statsDMaxPacketSize)
+	for {
+		n, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("Error reading statsd packet: %v", err)
+			continue
+		}
+		c.handlePacket(buffer[:n])
+	}
+}
+
+// handlePacket разбирает пакет, в котором может быть несколько строк
+func (c *StatsDCollector) handlePacket(packet []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, line := range strings.Split(string(packet), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		sample, err := parseStatsDLine(line)
+		if err != nil {
+			c.invalid++
+			c.lastErr = err
+			continue
+		}
+		c.add(sample)
+	}
+}
+
+func (c *StatsDCollector) add(sample statsDSample) {
+	switch sample.metricType {
+	case "c":
+		c.counters[sample.name] += sample.value / sample.rate
+	case "g":
+		if sample.relative {
+			c.gauges[sample.name] += sample.value
+		} else {
+			c.gauges[sample.name] = sample.value
+		}
+		c.updated[sample.name] = true
+	case "ms", "h":
+		timer, exists := c.timers[sample.name]
+		if !exists {
+			timer = &statsDTimer{}
+			c.timers[sample.name] = timer
+		}
+		timer.values = append(timer.values, sample.value)
+		timer.count += 1 / sample.rate
+	case "s":
+		set, exists := c.sets[sample.name]
+		if !exists {
+			set = make(map[string]struct{})
+			c.sets[sample.name] = set
+		}
+		set[sample.raw] = struct{}{}
+	}
+}
+
+// Collect возвращает значения, накопленные с прошлого вызова.
+// Дробная часть счетчиков переносится в следующий интервал.
+func (c *StatsDCollector) Collect(_ context.Context) ([]models.Metrics, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	metrics := make([]models.Metrics, 0, len(c.counters)+len(c.updated)+len(c.sets)+len(c.timers)*7)
+
+	for _, name := range sortedKeys(c.counters) {
+		delta := math.Trunc(c.counters[name])
+		if delta != 0 {
+			metrics = append(metrics, NewCounter(name, int64(delta)))
+		}
+		if c.counters[name] -= delta; c.counters[name] == 0 {
+			delete(c.counters, name)
+		}
+	}
+
+	for _, name := range sortedKeys(c.updated) {
+		metrics = append(metrics, NewGauge(name, c.gauges[name]))
+	}
+	c.updated = make(map[string]bool)
+
+	for _, name := range sortedKeys(c.sets) {
+		metrics = append(metrics, NewGauge(name, float64(len(c.sets[name]))))
+	}
+	c.sets = make(map[string]map[string]struct{})
+
+	for _, name := range sortedKeys(c.timers) {
+		metrics = append(metrics, timerMetrics(name, c.timers[name])...)
+	}
+	c.timers = make(map[string]*statsDTimer)
+
+	var err error
+	if c.invalid > 0 {
+		err = fmt.Errorf("invalid statsd lines in count (%d), last error: %v", c.invalid, c.lastErr)
+		c.invalid = 0
+		c.lastErr = nil
+	}
+	return metrics, err
+}
+
+func timerMetrics(name string, timer *statsDTimer) []models.Metrics {
+	values := timer.values
+	sort.Float64s(values)
+
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+
+	metrics := []models.Metrics{
+		NewCounter(name+".count", int64(math.Round(timer.count))),
+		NewGauge(name+".min", values[0]),
+		NewGauge(name+".max", values[len(values)-1]),
+		NewGauge(name+".mean", sum/float64(len(values))),
+	}
+	for _, q := range statsDQuantiles {
+		metrics = append(metrics, NewGauge(name+q.suffix, sortedQuantile(values, q.quantile)))
+	}
+	return metrics
+}
+
+// sortedQuantile возвращает квантиль отсортированной выборки методом ближайшего ранга
+func sortedQuantile(sorted []float64, quantile float64) float64 {
+	rank := int(math.Ceil(quantile * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// parseStatsDLine разбирает строку "name:value|type[|@rate][|#tags]", теги игнорируются
+func parseStatsDLine(line string) (statsDSample, error) {
+	name, rest, found := strings.Cut(line, ":")
+	if !found || name == "" {
+		return statsDSample{}, fmt.Errorf("invalid line %q: no metric name", line)
+	}
+
+	parts := strings.Split(rest, "|")
+	if len(parts) < 2 {
+		return statsDSample{}, fmt.Errorf("invalid line %q: no metric type", line)
+	}
+
+	sample := statsDSample{
+		name:       sanitizeMetricName(name),
+		raw:        parts[0],
+		metricType: parts[1],
+		rate:       1,
+	}
+
+	switch sample.metricType {
+	case "c", "g", "ms", "h":
+		value, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return statsDSample{}, fmt.Errorf("invalid value in line %q", line)
+		}
+		sample.value = value
+		sample.relative = sample.metricType == "g" && (parts[0][0] == '+' || parts[0][0] == '-')
+	case "s":
+	default:
+		return statsDSample{}, fmt.Errorf("unsupported metric type in line %q", line)
+	}
+
+	for _, part := range parts[2:] {
+		if !strings.HasPrefix(part, "@") {
+			continue
+		}
+		rate, err := strconv.ParseFloat(part[1:], 64)
+		if err != nil || rate <= 0 || rate > 1 {
+			return statsDSample{}, fmt.Errorf("invalid sample rate in line %q", line)
+		}
+		sample.rate = rate
+	}
+	return sample, nil
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
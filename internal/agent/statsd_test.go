@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatsDLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected statsDSample
+		wantErr  bool
+	}{
+		{line: "api.requests:1|c", expected: statsDSample{name: "api.requests", value: 1, raw: "1", metricType: "c", rate: 1}},
+		{line: "api.requests:2|c|@0.5", expected: statsDSample{name: "api.requests", value: 2, raw: "2", metricType: "c", rate: 0.5}},
+		{line: "queue size:-3|g|#env:prod", expected: statsDSample{name: "queue_size", value: -3, raw: "-3", metricType: "g", rate: 1, relative: true}},
+		{line: "api.latency:12.5|ms", expected: statsDSample{name: "api.latency", value: 12.5, raw: "12.5", metricType: "ms", rate: 1}},
+		{line: "api.users:alice|s", expected: statsDSample{name: "api.users", raw: "alice", metricType: "s", rate: 1}},
+		{line: "api.requests", wantErr: true},
+		{line: "api.requests:1", wantErr: true},
+		{line: "api.requests:abc|c", wantErr: true},
+		{line: "api.requests:1|x", wantErr: true},
+		{line: "api.requests:1|c|@2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			sample, err := parseStatsDLine(tt.line)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, sample)
+		})
+	}
+}
+
+func TestStatsDCollector_Collect(t *testing.T) {
+	collector, err := NewStatsDCollector(time.Second, defaultStatsDOptions())
+	require.NoError(t, err)
+
+	collector.handlePacket([]byte("requests:1|c\nrequests:1|c|@0.5\nrequests:0.5|c\n" +
+		"temperature:20|g\ntemperature:+2|g\n" +
+		"latency:10|ms\nlatency:30|ms\nlatency:20|ms\n" +
+		"users:alice|s\nusers:bob|s\nusers:alice|s\n" +
+		"broken line\n"))
+
+	metrics, err := collector.Collect(context.Background())
+	assert.Error(t, err)
+
+	counters := counterValues(metrics)
+	assert.Equal(t, int64(3), counters["requests"])
+	assert.Equal(t, int64(3), counters["latency.count"])
+
+	gauges := gaugeValues(metrics)
+	assert.Equal(t, 22.0, gauges["temperature"])
+	assert.Equal(t, 2.0, gauges["users"])
+	assert.Equal(t, 10.0, gauges["latency.min"])
+	assert.Equal(t, 30.0, gauges["latency.max"])
+	assert.Equal(t, 20.0, gauges["latency.mean"])
+	assert.Equal(t, 20.0, gauges["latency.p50"])
+
+	// дробная часть счетчика переносится в следующий интервал, без новых значений ничего не отправляется
+	collector.handlePacket([]byte("requests:0.5|c"))
+	metrics, err = collector.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"requests": 1}, counterValues(metrics))
+	assert.Empty(t, gaugeValues(metrics))
+}
+
+func TestStatsDCollector_Listen(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "statsd.sock")
+	collector, err := NewStatsDCollector(time.Second, StatsDOptions{Address: "127.0.0.1:0", UnixSocket: socket})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, collector.Start(ctx))
+	require.Len(t, collector.conns, 2)
+
+	udp, err := net.Dial("udp", collector.conns[0].LocalAddr().String())
+	require.NoError(t, err)
+	defer udp.Close()
+	_, err = udp.Write([]byte("udp.hits:2|c"))
+	require.NoError(t, err)
+
+	unix, err := net.Dial("unixgram", socket)
+	require.NoError(t, err)
+	defer unix.Close()
+	_, err = unix.Write([]byte("unix.hits:3|c"))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		collector.mutex.Lock()
+		defer collector.mutex.Unlock()
+		return collector.counters["udp.hits"] == 2 && collector.counters["unix.hits"] == 3
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestNewStatsDCollector_NoAddress(t *testing.T) {
+	_, err := NewStatsDCollector(time.Second, StatsDOptions{})
+	assert.Error(t, err)
+}
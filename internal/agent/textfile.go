@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+)
+
+const TextfileCollectorName = "textfile"
+
+const textfileExtension = ".prom"
+
+// TextfileOptions настройки коллектора текстовых файлов
+type TextfileOptions struct {
+	// Directory каталог с файлами *.prom
+	Directory string `json:"directory"`
+}
+
+func init() {
+	RegisterCollector(TextfileCollectorName, func(interval time.Duration, options json.RawMessage) (Collector, error) {
+		textfileOptions := TextfileOptions{}
+		if err := decodeOptions(options, &textfileOptions); err != nil {
+			return nil, err
+		}
+		return NewTextfileCollector(interval, textfileOptions)
+	}, false)
+}
+
+// TextfileCollector читает файлы *.prom в текстовом формате Prometheus, как textfile collector из node_exporter.
+// Так метрики отправляют cron-задачи и скрипты, которые не могут держать соединение с агентом.
+// Файлы с ошибками пропускаются, для каждого файла отправляется TextfileError_<file> со значением 1 или 0
+// и время его изменения в TextfileMtime_<file>.
+type TextfileCollector struct {
+	interval  time.Duration
+	directory string
+	counters  *cumulativeCounters
+}
+
+func NewTextfileCollector(interval time.Duration, options TextfileOptions) (*TextfileCollector, error) {
+	if options.Directory == "" {
+		return nil, errors.New("textfile directory is required")
+	}
+
+	return &TextfileCollector{
+		interval:  interval,
+		directory: options.Directory,
+		counters:  newCumulativeCounters(),
+	}, nil
+}
+
+func (c *TextfileCollector) Name() string {
+	return TextfileCollectorName
+}
+
+func (c *TextfileCollector) Interval() time.Duration {
+	return c.interval
+}
+
+func (c *TextfileCollector) Collect(_ context.Context) ([]models.Metrics, error) {
+	entries, err := os.ReadDir(c.directory)
+	if err != nil {
+		return nil, fmt.Errorf("error reading textfile directory: %v", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), textfileExtension) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	metrics := make([]models.Metrics, 0)
+	errs := make([]error, 0)
+	for _, name := range names {
+		label := strings.TrimSuffix(name, textfileExtension)
+
+		fileMetrics, modified, err := c.readFile(filepath.Join(c.directory, name))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("textfile %s: %v", name, err))
+			metrics = append(metrics, NewGauge(labeledName("TextfileError", label), 1))
+			continue
+		}
+
+		metrics = append(metrics,
+			NewGauge(labeledName("TextfileError", label), 0),
+			NewGauge(labeledName("TextfileMtime", label), float64(modified.Unix())),
+		)
+		metrics = append(metrics, fileMetrics...)
+	}
+	return metrics, errors.Join(errs...)
+}
+
+// readFile разбирает файл целиком, чтобы частично записанный файл не отправлялся наполовину
+func (c *TextfileCollector) readFile(path string) ([]models.Metrics, time.Time, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	samples, err := parsePrometheusText(file)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return promMetrics("", samples, c.counters), info.ModTime(), nil
+}
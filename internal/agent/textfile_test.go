@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextfileCollector_Collect(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	write("backup.prom", "# TYPE backup_last_success gauge\nbackup_last_success 1700000000\n"+
+		"# TYPE backup_files_total counter\nbackup_files_total 100\n")
+	write("broken.prom", "job_status{job=\"x\" 1\n")
+	write("notes.txt", "not a metric 1\n")
+
+	collector, err := NewTextfileCollector(time.Second, TextfileOptions{Directory: dir})
+	require.NoError(t, err)
+
+	first, err := collector.Collect(context.Background())
+	assert.ErrorContains(t, err, "broken.prom")
+
+	gauges := gaugeValues(first)
+	assert.Equal(t, 1700000000.0, gauges["backup_last_success"])
+	assert.Equal(t, 0.0, gauges["TextfileError_backup"])
+	assert.Equal(t, 1.0, gauges["TextfileError_broken"])
+	assert.Contains(t, gauges, "TextfileMtime_backup")
+	assert.NotContains(t, gauges, "TextfileError_notes")
+	assert.Empty(t, counterValues(first))
+
+	write("backup.prom", "# TYPE backup_files_total counter\nbackup_files_total 130\n")
+	write("broken.prom", "job_status{job=\"x\"} 1\n")
+
+	second, err := collector.Collect(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]int64{"backup_files_total": 30}, counterValues(second))
+	gauges = gaugeValues(second)
+	assert.Equal(t, 0.0, gauges["TextfileError_broken"])
+	assert.Equal(t, 1.0, gauges["job_status_job_x"])
+}
+
+func TestTextfileCollector_MissingDirectory(t *testing.T) {
+	collector, err := NewTextfileCollector(time.Second, TextfileOptions{Directory: filepath.Join(t.TempDir(), "missing")})
+	require.NoError(t, err)
+
+	_, err = collector.Collect(context.Background())
+	assert.Error(t, err)
+
+	_, err = NewTextfileCollector(time.Second, TextfileOptions{})
+	assert.Error(t, err)
+}
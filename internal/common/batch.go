@@ -0,0 +1,23 @@
+package common
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// BatchIDHeader заголовок с идентификатором пачки метрик для идемпотентной доставки
+const BatchIDHeader = "X-Batch-Id"
+
+// NewBatchID генерирует случайный идентификатор пачки в формате UUID v4
+func NewBatchID() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", fmt.Errorf("failed to generate batch id: %v", err)
+	}
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+
+	buf := hex.EncodeToString(id[:])
+	return fmt.Sprintf("%s-%s-%s-%s-%s", buf[0:8], buf[8:12], buf[12:16], buf[16:20], buf[20:32]), nil
+}
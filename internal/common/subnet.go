@@ -0,0 +1,53 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// RealIPHeader заголовок с адресом агента
+const RealIPHeader = "X-Real-IP"
+
+// ParseSubnets разбирает список подсетей CIDR через запятую, пустая строка — пустой список
+func ParseSubnets(value string) ([]*net.IPNet, error) {
+	subnets := make([]*net.IPNet, 0)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		_, subnet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted subnet %q: %v", item, err)
+		}
+		subnets = append(subnets, subnet)
+	}
+	return subnets, nil
+}
+
+// ContainsIP проверяет, входит ли адрес хотя бы в одну из подсетей
+func ContainsIP(subnets []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, subnet := range subnets {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTrustedAgent проверяет адреса агента: адрес соединения peerIP и адрес realIP из заголовка X-Real-IP.
+// Без trustProxy в доверенных подсетях должны быть оба адреса (заголовок необязателен).
+// С trustProxy запросы приходят через обратный прокси, поэтому проверяется только обязательный заголовок.
+func IsTrustedAgent(subnets []*net.IPNet, peerIP net.IP, realIP string, trustProxy bool) bool {
+	if realIP != "" && !ContainsIP(subnets, net.ParseIP(realIP)) {
+		return false
+	}
+	if trustProxy {
+		return realIP != ""
+	}
+	return ContainsIP(subnets, peerIP)
+}
@@ -22,10 +22,25 @@ type Config struct {
 	DatabaseDNS string `env:"DATABASE_DSN"`
 	// KeyHash Хэш-ключ
 	KeyHash string `env:"KEY"`
+	// KeyRing путь к JSON-файлу ключей агентов с идентификаторами и сроками действия
+	KeyRing string `env:"KEY_RING"`
 	//AuditFile путь для сохранения аудит данных в файл
 	AuditFile string `env:"AUDIT_FILE"`
 	//AuditURL аддрес сервера для сохранения аудит данных в файл
 	AuditURL string `env:"AUDIT_URL"`
+	// GRPCAddress адрес и порт gRPC-сервера, пустой — gRPC-сервер не запускается
+	GRPCAddress string `env:"GRPC_ADDRESS"`
+	// TrustedSubnet доверенные подсети агентов в формате CIDR через запятую
+	TrustedSubnet string `env:"TRUSTED_SUBNET"`
+	// TrustProxy сервер стоит за обратным прокси: адрес агента берется только из заголовка X-Real-IP
+	TrustProxy bool `env:"TRUST_PROXY"`
+	// CryptoKey путь к закрытому ключу RSA для расшифровки запросов агента
+	CryptoKey string `env:"CRYPTO_KEY"`
+	// TLSCert и TLSKey сертификат и ключ сервера, с ними HTTP и gRPC работают по TLS
+	TLSCert string `env:"TLS_CERT"`
+	TLSKey  string `env:"TLS_KEY"`
+	// TLSClientCA CA клиентских сертификатов, с ним сервер требует сертификат агента (mTLS)
+	TLSClientCA string `env:"TLS_CLIENT_CA"`
 }
 
 func InitConfig() *Config {
@@ -39,8 +54,16 @@ func InitConfig() *Config {
 		Restore:         flags.restore,
 		DatabaseDNS:     flags.dbDNS,
 		KeyHash:         flags.keyHash,
+		KeyRing:         flags.keyRing,
 		AuditFile:       flags.auditFile,
 		AuditURL:        flags.auditURL,
+		GRPCAddress:     flags.grpcAddress,
+		TrustedSubnet:   flags.trustedSubnet,
+		TrustProxy:      flags.trustProxy,
+		CryptoKey:       flags.cryptoKey,
+		TLSCert:         flags.tlsCert,
+		TLSKey:          flags.tlsKey,
+		TLSClientCA:     flags.tlsClientCA,
 	}
 	cfg.parseEnv()
 
@@ -16,8 +16,16 @@ type ServerFlags struct {
 	restore         bool
 	dbDNS           string
 	keyHash         string
+	keyRing         string
 	auditFile       string
 	auditURL        string
+	grpcAddress     string
+	trustedSubnet   string
+	trustProxy      bool
+	cryptoKey       string
+	tlsCert         string
+	tlsKey          string
+	tlsClientCA     string
 }
 
 func (flags *ServerFlags) Init() {
@@ -29,9 +37,19 @@ func (flags *ServerFlags) Init() {
 
 	flag.StringVar(&flags.dbDNS, "d", defaultDBDNS, "db dns")
 	flag.StringVar(&flags.keyHash, "k", "", "key for hash")
+	flag.StringVar(&flags.keyRing, "key-ring", "", "path to JSON file with agent keys by Key-Id")
 
 	flag.StringVar(&flags.auditFile, "audit-file", "", "path to audit file")
 	flag.StringVar(&flags.auditURL, "audit-url", "", "address for applying audit data")
 
+	flag.StringVar(&flags.grpcAddress, "grpc-address", "", "address and port to run gRPC server")
+	flag.StringVar(&flags.trustedSubnet, "t", "", "trusted subnets of agents in CIDR notation, comma separated")
+	flag.BoolVar(&flags.trustProxy, "trust-proxy", false, "take agent address only from X-Real-IP header set by reverse proxy")
+	flag.StringVar(&flags.cryptoKey, "crypto-key", "", "path to RSA private key for decrypting agent requests")
+
+	flag.StringVar(&flags.tlsCert, "tls-cert", "", "path to TLS certificate of server")
+	flag.StringVar(&flags.tlsKey, "tls-key", "", "path to TLS key of server")
+	flag.StringVar(&flags.tlsClientCA, "tls-client-ca", "", "path to CA of agent certificates, enables mutual TLS")
+
 	flag.Parse()
 }
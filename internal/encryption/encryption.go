@@ -0,0 +1,175 @@
+// Package encryption шифрует тела запросов агента открытым ключом RSA сервера
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Header заголовок зашифрованного запроса, значение — схема шифрования
+const Header = "X-Encryption"
+
+// Scheme RSA-OAEP с SHA-256, для больших тел — вместе с AES-256-GCM
+const Scheme = "rsa-oaep-aes-gcm"
+
+// режимы шифрования, первый байт зашифрованных данных
+const (
+	// modeRSA данные целиком помещаются в один блок RSA-OAEP
+	modeRSA byte = 1
+	// modeHybrid случайный ключ AES зашифрован RSA-OAEP, данные — AES-GCM
+	modeHybrid byte = 2
+)
+
+const aesKeySize = 32
+
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// LoadPublicKey читает открытый ключ RSA из PEM-файла в формате PKIX или PKCS#1
+func LoadPublicKey(path string) (*rsa.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key %s: %v", path, err)
+	}
+	publicKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %s is not RSA", path)
+	}
+	return publicKey, nil
+}
+
+// LoadPrivateKey читает закрытый ключ RSA из PEM-файла в формате PKCS#8 или PKCS#1
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key %s: %v", path, err)
+	}
+	privateKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key %s is not RSA", path)
+	}
+	return privateKey, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+	return block, nil
+}
+
+// Encrypt шифрует данные открытым ключом. Данные, которые не помещаются в блок RSA-OAEP,
+// шифруются AES-GCM случайным ключом, а сам ключ — RSA-OAEP.
+func Encrypt(publicKey *rsa.PublicKey, data []byte) ([]byte, error) {
+	if len(data) <= publicKey.Size()-2*sha256.Size-2 {
+		encrypted, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt data: %v", err)
+		}
+		return append([]byte{modeRSA}, encrypted...), nil
+	}
+
+	aesKey := make([]byte, aesKeySize)
+	if _, err := rand.Read(aesKey); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %v", err)
+	}
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, aesKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt key: %v", err)
+	}
+
+	gcm, err := newGCM(aesKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	// режим | ключ AES, зашифрованный RSA | nonce | данные AES-GCM
+	encrypted := make([]byte, 0, 1+len(wrappedKey)+len(nonce)+len(data)+gcm.Overhead())
+	encrypted = append(encrypted, modeHybrid)
+	encrypted = append(encrypted, wrappedKey...)
+	encrypted = append(encrypted, nonce...)
+	return gcm.Seal(encrypted, nonce, data, nil), nil
+}
+
+// Decrypt расшифровывает данные, зашифрованные Encrypt
+func Decrypt(privateKey *rsa.PrivateKey, data []byte) ([]byte, error) {
+	if len(data) < 1+privateKey.Size() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	mode, data := data[0], data[1:]
+	switch mode {
+	case modeRSA:
+		decrypted, err := rsa.DecryptOAEP(sha256.New(), nil, privateKey, data, nil)
+		if err != nil {
+			return nil, ErrInvalidCiphertext
+		}
+		return decrypted, nil
+	case modeHybrid:
+		wrappedKey, data := data[:privateKey.Size()], data[privateKey.Size():]
+		aesKey, err := rsa.DecryptOAEP(sha256.New(), nil, privateKey, wrappedKey, nil)
+		if err != nil || len(aesKey) != aesKeySize {
+			return nil, ErrInvalidCiphertext
+		}
+
+		gcm, err := newGCM(aesKey)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) < gcm.NonceSize() {
+			return nil, ErrInvalidCiphertext
+		}
+		nonce, data := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+		decrypted, err := gcm.Open(nil, nonce, data, nil)
+		if err != nil {
+			return nil, ErrInvalidCiphertext
+		}
+		return decrypted, nil
+	default:
+		return nil, ErrInvalidCiphertext
+	}
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init cipher: %v", err)
+	}
+	return gcm, nil
+}
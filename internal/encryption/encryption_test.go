@@ -0,0 +1,114 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func TestEncrypt_RoundTrip(t *testing.T) {
+	key := generateKey(t)
+
+	tests := map[string][]byte{
+		"small": []byte(`[{"id":"Alloc","type":"gauge","value":1}]`),
+		"large": bytes.Repeat([]byte("metrics"), 10000),
+		"empty": {},
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			encrypted, err := Encrypt(&key.PublicKey, data)
+			require.NoError(t, err)
+			if len(data) > 0 {
+				assert.False(t, bytes.Contains(encrypted, data))
+			}
+
+			decrypted, err := Decrypt(key, encrypted)
+			require.NoError(t, err)
+			assert.Equal(t, data, decrypted)
+		})
+	}
+}
+
+func TestEncrypt_Modes(t *testing.T) {
+	key := generateKey(t)
+
+	small, err := Encrypt(&key.PublicKey, []byte("data"))
+	require.NoError(t, err)
+	assert.Equal(t, modeRSA, small[0])
+	assert.Len(t, small, 1+key.Size())
+
+	large, err := Encrypt(&key.PublicKey, make([]byte, key.Size()))
+	require.NoError(t, err)
+	assert.Equal(t, modeHybrid, large[0])
+}
+
+func TestDecrypt_Invalid(t *testing.T) {
+	key := generateKey(t)
+	other := generateKey(t)
+
+	encrypted, err := Encrypt(&key.PublicKey, bytes.Repeat([]byte("metrics"), 100))
+	require.NoError(t, err)
+
+	_, err = Decrypt(other, encrypted)
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	tampered := append([]byte(nil), encrypted...)
+	tampered[len(tampered)-1] ^= 0xff
+	_, err = Decrypt(key, tampered)
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	_, err = Decrypt(key, []byte("short"))
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+}
+
+func TestLoadKeys(t *testing.T) {
+	key := generateKey(t)
+	dir := t.TempDir()
+
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	privateDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	files := map[string]*pem.Block{
+		"public.pem":      {Type: "PUBLIC KEY", Bytes: publicDER},
+		"public_rsa.pem":  {Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&key.PublicKey)},
+		"private.pem":     {Type: "PRIVATE KEY", Bytes: privateDER},
+		"private_rsa.pem": {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+		"not_a_key.pem":   {Type: "PUBLIC KEY", Bytes: []byte("garbage")},
+	}
+	for name, block := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0600))
+	}
+
+	for _, name := range []string{"public.pem", "public_rsa.pem"} {
+		publicKey, err := LoadPublicKey(filepath.Join(dir, name))
+		require.NoError(t, err, name)
+		assert.True(t, key.PublicKey.Equal(publicKey), name)
+	}
+	for _, name := range []string{"private.pem", "private_rsa.pem"} {
+		privateKey, err := LoadPrivateKey(filepath.Join(dir, name))
+		require.NoError(t, err, name)
+		assert.True(t, key.Equal(privateKey), name)
+	}
+
+	_, err = LoadPublicKey(filepath.Join(dir, "not_a_key.pem"))
+	assert.Error(t, err)
+	_, err = LoadPrivateKey(filepath.Join(dir, "missing.pem"))
+	assert.Error(t, err)
+}
@@ -2,6 +2,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"log"
 
@@ -9,14 +10,47 @@ import (
 	"github.com/Bessima/metrics-collect/internal/repository"
 )
 
-func updateMetricInStorage(storage repository.StorageRepositorier, metric models.Metrics) error {
+// ErrInvalidMetric означает, что метрика в пачке некорректна и повтор пачки ничего не изменит.
+// Остальные ошибки ApplyBatch — сбои хранилища, после которых пачку можно отправить снова.
+var ErrInvalidMetric = errors.New("invalid metric")
+
+// IsInvalidBatch сообщает, что пачка отклонена только из-за некорректных метрик
+func IsInvalidBatch(err error) bool {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			if !IsInvalidBatch(e) {
+				return false
+			}
+		}
+		return true
+	}
+	return errors.Is(err, ErrInvalidMetric)
+}
+
+// validateMetric проверяет, что метрику можно сохранить, не изменяя хранилище
+func validateMetric(metric models.Metrics) error {
 	switch repository.TypeMetric(metric.MType) {
 	case repository.TypeCounter:
 		if metric.Delta == nil {
-			return fmt.Errorf("delta value not found for %s", metric.ID)
+			return fmt.Errorf("%w: delta value not found for %s", ErrInvalidMetric, metric.ID)
 		}
-		delta := *metric.Delta
-		if err := storage.Counter(metric.ID, delta); err != nil {
+	case repository.TypeGauge:
+		if metric.Value == nil {
+			return fmt.Errorf("%w: value not found for %s", ErrInvalidMetric, metric.ID)
+		}
+	default:
+		return fmt.Errorf("%w: type %s not supported", ErrInvalidMetric, metric.MType)
+	}
+	return nil
+}
+
+func updateMetricInStorage(storage repository.StorageRepositorier, metric models.Metrics) error {
+	if err := validateMetric(metric); err != nil {
+		return err
+	}
+
+	if repository.TypeMetric(metric.MType) == repository.TypeCounter {
+		if err := storage.Counter(metric.ID, *metric.Delta); err != nil {
 			return fmt.Errorf("failed to change delta of counter metric, error: %s", err)
 		}
 		newValue, err := storage.GetValue(models.Counter, metric.ID)
@@ -25,22 +59,72 @@ func updateMetricInStorage(storage repository.StorageRepositorier, metric models
 		}
 		log.Println("Successful counter: ", metric.ID, newValue)
 		return nil
-	case repository.TypeGauge:
-		if metric.Value == nil {
-			return fmt.Errorf("value not found for %s", metric.ID)
-		}
-		value := *metric.Value
-		if err := storage.ReplaceGaugeMetric(metric.ID, value); err != nil {
-			return fmt.Errorf("failed to change value of gauge metric, error: %s", err)
+	}
+
+	if err := storage.ReplaceGaugeMetric(metric.ID, *metric.Value); err != nil {
+		return fmt.Errorf("failed to change value of gauge metric, error: %s", err)
+	}
+	newValue, err := storage.GetValue(models.Gauge, metric.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get value of counter metric, error: %s", err)
+	}
+	log.Println("Successful replacing gauge: ", metric.ID, newValue)
+	return nil
+}
+
+// ApplyBatch сохраняет пачку метрик. Пачка с batchID применяется целиком или не применяется совсем:
+// идентификатор занимается до применения, а при ошибке освобождается, чтобы пачку можно было отправить снова.
+// Иначе повтор после частично примененной пачки учел бы часть счетчиков дважды.
+// Пачка без batchID применяется по одной метрике, как раньше.
+// duplicate сообщает, что пачка уже была применена ранее.
+func ApplyBatch(storage repository.StorageRepositorier, batchID string, metrics []models.Metrics) (duplicate bool, err error) {
+	registry, hasRegistry := storage.(repository.BatchRegistry)
+	applier, hasApplier := storage.(repository.BatchApplier)
+	if batchID == "" || (!hasRegistry && !hasApplier) {
+		return false, applyMetrics(storage, metrics)
+	}
+
+	for _, metric := range metrics {
+		if err = validateMetric(metric); err != nil {
+			return false, err
 		}
-		newValue, err := storage.GetValue(models.Gauge, metric.ID)
-		if err != nil {
-			return fmt.Errorf("failed to get value of counter metric, error: %s", err)
+	}
+
+	if hasApplier {
+		duplicate, err = applier.ApplyBatch(batchID, metrics)
+	} else {
+		duplicate, err = applyClaimedBatch(storage, registry, batchID, metrics)
+	}
+	if duplicate {
+		log.Println("Batch was already applied: ", batchID)
+	}
+	return duplicate, err
+}
+
+func applyClaimedBatch(storage repository.StorageRepositorier, registry repository.BatchRegistry, batchID string, metrics []models.Metrics) (bool, error) {
+	claimed, err := registry.ClaimBatch(batchID)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim batch id: %v", err)
+	}
+	if !claimed {
+		return true, nil
+	}
+
+	if err = applyMetrics(storage, metrics); err != nil {
+		if releaseErr := registry.ReleaseBatch(batchID); releaseErr != nil {
+			log.Println("Failed to release batch id, error: ", releaseErr)
 		}
-		log.Println("Successful replacing gauge: ", metric.ID, newValue)
-		return nil
-	default:
-		return fmt.Errorf("type %s not supported", metric.MType)
+		return false, err
 	}
+	return false, nil
+}
 
+func applyMetrics(storage repository.StorageRepositorier, metrics []models.Metrics) error {
+	errs := make([]error, 0)
+	for _, metric := range metrics {
+		if err := updateMetricInStorage(storage, metric); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/Bessima/metrics-collect/internal/common"
 	models "github.com/Bessima/metrics-collect/internal/model"
 	"github.com/Bessima/metrics-collect/internal/repository"
 	"github.com/Bessima/metrics-collect/pkg/audit"
@@ -30,10 +31,20 @@ func UpdatesHandler(storage repository.StorageRepositorier, metricsFromFile *rep
 
 		for _, metric := range metrics {
 			metricsNames = append(metricsNames, metric.ID)
-			err := updateMetricInStorage(storage, metric)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+
+		duplicate, err := ApplyBatch(storage, r.Header.Get(common.BatchIDHeader), metrics)
+		if duplicate {
+			return
+		}
+		if err != nil {
+			// некорректную пачку агент отбрасывает, а после сбоя хранилища отправит ее снова
+			statusCode := http.StatusInternalServerError
+			if IsInvalidBatch(err) {
+				statusCode = http.StatusBadRequest
 			}
+			http.Error(w, err.Error(), statusCode)
+			return
 		}
 
 		if metricsFromFile != nil {
@@ -41,7 +52,7 @@ func UpdatesHandler(storage repository.StorageRepositorier, metricsFromFile *rep
 		}
 
 		if auditEvent != nil {
-			auditEvent.Notify(metricsNames, r.RemoteAddr)
+			auditEvent.Notify(metricsNames, r.RemoteAddr, common.PeerIdentity(r.TLS))
 		}
 	}
 }
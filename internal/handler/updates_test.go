@@ -3,12 +3,15 @@ package handler
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 
+	"github.com/Bessima/metrics-collect/internal/common"
 	models "github.com/Bessima/metrics-collect/internal/model"
 	"github.com/Bessima/metrics-collect/internal/repository"
 	"github.com/Bessima/metrics-collect/pkg/audit"
@@ -449,3 +452,188 @@ func TestUpdatesHandler_NilBody(t *testing.T) {
 	// Should handle nil body gracefully
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
+
+func TestUpdatesHandler_DuplicateBatchID(t *testing.T) {
+	storage := repository.NewMemStorage()
+	handler := UpdatesHandler(storage, nil, nil)
+
+	counter := int64(5)
+	metrics := []models.Metrics{
+		{
+			ID:    "PollCount",
+			MType: models.Counter,
+			Delta: &counter,
+		},
+	}
+
+	body, err := json.Marshal(metrics)
+	require.NoError(t, err)
+
+	// Повторная доставка той же пачки не должна изменять счетчик
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(common.BatchIDHeader, "b4c1a8e2-3f7d-4c5b-9a2e-6d8f0e1c2b3a")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	val, err := storage.GetValue(repository.TypeCounter, "PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), val)
+}
+
+func TestUpdatesHandler_DifferentBatchIDs(t *testing.T) {
+	storage := repository.NewMemStorage()
+	handler := UpdatesHandler(storage, nil, nil)
+
+	counter := int64(5)
+	metrics := []models.Metrics{
+		{
+			ID:    "PollCount",
+			MType: models.Counter,
+			Delta: &counter,
+		},
+	}
+
+	body, err := json.Marshal(metrics)
+	require.NoError(t, err)
+
+	for _, batchID := range []string{"first-batch", "second-batch"} {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(common.BatchIDHeader, batchID)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	val, err := storage.GetValue(repository.TypeCounter, "PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), val)
+}
+
+func TestUpdatesHandler_ConcurrentSameBatchID(t *testing.T) {
+	storage := repository.NewMemStorage()
+	handler := UpdatesHandler(storage, nil, nil)
+
+	counter := int64(5)
+	body, err := json.Marshal([]models.Metrics{{ID: "PollCount", MType: models.Counter, Delta: &counter}})
+	require.NoError(t, err)
+
+	// параллельные повторы одной пачки применяют ее только один раз
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(common.BatchIDHeader, "concurrent-batch")
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}()
+	}
+	wg.Wait()
+
+	val, err := storage.GetValue(repository.TypeCounter, "PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), val)
+}
+
+func TestUpdatesHandler_InvalidBatchIsNotApplied(t *testing.T) {
+	storage := repository.NewMemStorage()
+	handler := UpdatesHandler(storage, nil, nil)
+
+	counter := int64(10)
+	send := func(metrics []models.Metrics) int {
+		body, err := json.Marshal(metrics)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(common.BatchIDHeader, "partial-batch")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// пачка с ошибкой не применяется и не занимает идентификатор
+	code := send([]models.Metrics{
+		{ID: "valid_counter", MType: models.Counter, Delta: &counter},
+		{ID: "invalid_counter", MType: models.Counter},
+	})
+	assert.Equal(t, http.StatusBadRequest, code)
+	_, err := storage.GetValue(repository.TypeCounter, "valid_counter")
+	assert.ErrorIs(t, err, repository.ErrMetricNotFound)
+
+	assert.Equal(t, http.StatusOK, send([]models.Metrics{{ID: "valid_counter", MType: models.Counter, Delta: &counter}}))
+	val, err := storage.GetValue(repository.TypeCounter, "valid_counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), val)
+}
+
+// failingStorage имитирует недоступное хранилище
+type failingStorage struct {
+	*repository.MemStorage
+}
+
+func (storage failingStorage) Counter(string, int64) error {
+	return errors.New("connection refused")
+}
+
+func TestUpdatesHandler_StorageFailure(t *testing.T) {
+	handler := UpdatesHandler(failingStorage{repository.NewMemStorage()}, nil, nil)
+
+	counter := int64(5)
+	body, err := json.Marshal([]models.Metrics{{ID: "PollCount", MType: models.Counter, Delta: &counter}})
+	require.NoError(t, err)
+
+	// сбой хранилища не должен выглядеть как некорректная пачка, иначе агент ее отбросит
+	for _, batchID := range []string{"", "failed-batch"} {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(common.BatchIDHeader, batchID)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	}
+}
+
+func TestUpdatesHandler_RejectedBatchIsNotAudited(t *testing.T) {
+	auditFile := filepath.Join(t.TempDir(), "audit.log")
+
+	auditEvent := &audit.Event{}
+	auditEvent.Register(audit.NewFileSubscriber(auditFile))
+	handler := UpdatesHandler(repository.NewMemStorage(), nil, auditEvent)
+
+	counter := int64(10)
+	body, err := json.Marshal([]models.Metrics{
+		{ID: "valid_counter", MType: models.Counter, Delta: &counter},
+		{ID: "invalid_counter", MType: models.Counter},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(common.BatchIDHeader, "rejected-batch")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// метрики отклоненной пачки не применены, поэтому в аудит они не попадают
+	auditData, err := os.ReadFile(auditFile)
+	require.NoError(t, err)
+	assert.Empty(t, auditData)
+}
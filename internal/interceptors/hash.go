@@ -0,0 +1,204 @@
+// Package interceptors содержит перехватчики gRPC, аналогичные HTTP-middleware сервера
+package interceptors
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/Bessima/metrics-collect/internal/common"
+	"github.com/Bessima/metrics-collect/internal/keyring"
+	"github.com/Bessima/metrics-collect/internal/metricspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// BatchSigner подписывает метрики одного потока цепочкой HMAC-SHA256. Это аналог заголовка common.HashHeader:
+// в потоке заголовок не может зависеть от тела, поэтому подпись каждой метрики в поле hash покрывает
+// идентификатор пачки, подпись предыдущей метрики и признак последней метрики. Так метрики нельзя
+// удалить, переставить или перенести в другую пачку, не нарушив подписи.
+type BatchSigner struct {
+	key     string
+	batchID string
+	prev    string
+}
+
+// NewBatchSigner создает подпись для пачки batchID
+func NewBatchSigner(key string, batchID string) *BatchSigner {
+	return &BatchSigner{key: key, batchID: batchID}
+}
+
+// Sign записывает в поле hash подпись очередной метрики, final отмечает последнюю метрику пачки
+func (signer *BatchSigner) Sign(metric *metricspb.Metric, final bool) error {
+	hash, err := signer.hash(metric, final)
+	if err != nil {
+		return err
+	}
+	metric.Hash = hash
+	signer.prev = hash
+	return nil
+}
+
+// Verify проверяет подпись очередной метрики и сообщает, последняя ли она в пачке
+func (signer *BatchSigner) Verify(metric *metricspb.Metric) (final bool, ok bool) {
+	if metric.Hash == "" {
+		return false, false
+	}
+	for _, final = range []bool{false, true} {
+		hash, err := signer.hash(metric, final)
+		if err != nil {
+			return false, false
+		}
+		if hmac.Equal([]byte(metric.Hash), []byte(hash)) {
+			signer.prev = metric.Hash
+			return final, true
+		}
+	}
+	return false, false
+}
+
+// hash считает HMAC метрики с пустым hash, поля разделяются префиксами длины
+func (signer *BatchSigner) hash(metric *metricspb.Metric, final bool) (string, error) {
+	hash := metric.Hash
+	metric.Hash = ""
+	message, err := proto.MarshalOptions{Deterministic: true}.Marshal(metric)
+	metric.Hash = hash
+	if err != nil {
+		return "", err
+	}
+
+	data := make([]byte, 0, len(signer.batchID)+len(signer.prev)+len(message)+16)
+	for _, field := range [][]byte{[]byte(signer.batchID), []byte(signer.prev), message} {
+		data = binary.AppendUvarint(data, uint64(len(field)))
+		data = append(data, field...)
+	}
+	if final {
+		data = append(data, 1)
+	} else {
+		data = append(data, 0)
+	}
+	return common.GetHashData(data, signer.key), nil
+}
+
+// batchIDFromMetadata возвращает идентификатор пачки из метаданных common.BatchIDHeader
+func batchIDFromMetadata(md metadata.MD) string {
+	if values := md.Get(common.BatchIDHeader); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// HashStreamClientInterceptor подписывает отправляемые метрики ключом агента через BatchSigner
+// с идентификатором пачки из метаданных common.BatchIDHeader и передает его идентификатор keyID в метаданных keyring.Header
+func HashStreamClientInterceptor(key string, keyID string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if key != "" && keyID != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, keyring.Header, keyID)
+		}
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil || key == "" {
+			return stream, err
+		}
+		md, _ := metadata.FromOutgoingContext(ctx)
+		return &signingClientStream{ClientStream: stream, signer: NewBatchSigner(key, batchIDFromMetadata(md))}, nil
+	}
+}
+
+// signingClientStream придерживает последнюю метрику до CloseSend, чтобы подписать ее как последнюю в пачке
+type signingClientStream struct {
+	grpc.ClientStream
+	signer  *BatchSigner
+	pending *metricspb.Metric
+}
+
+func (s *signingClientStream) SendMsg(m any) error {
+	metric, ok := m.(*metricspb.Metric)
+	if !ok {
+		return s.ClientStream.SendMsg(m)
+	}
+
+	pending := s.pending
+	s.pending = proto.Clone(metric).(*metricspb.Metric)
+	if pending == nil {
+		return nil
+	}
+	return s.sendSigned(pending, false)
+}
+
+func (s *signingClientStream) CloseSend() error {
+	if s.pending != nil {
+		pending := s.pending
+		s.pending = nil
+		// при io.EOF сервер уже закрыл поток, причину вернет RecvMsg
+		if err := s.sendSigned(pending, true); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+	return s.ClientStream.CloseSend()
+}
+
+func (s *signingClientStream) sendSigned(metric *metricspb.Metric, final bool) error {
+	if err := s.signer.Sign(metric, final); err != nil {
+		return status.Errorf(codes.Internal, "failed to sign metric: %v", err)
+	}
+	return s.ClientStream.SendMsg(metric)
+}
+
+// HashStreamServerInterceptor отклоняет поток, если подпись метрик не сходится с ключом агента
+// из метаданных keyring.Header, а без идентификатора — с общим ключом, или пачка пришла не целиком.
+// Без ключей метрики не проверяются.
+func HashStreamServerInterceptor(keys *keyring.KeyRing) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !keys.Enabled() {
+			return handler(srv, stream)
+		}
+
+		keyID := ""
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		if values := md.Get(keyring.Header); len(values) > 0 {
+			keyID = values[0]
+		}
+		key, err := keys.Secret(keyID)
+		if err != nil {
+			return status.Errorf(codes.Unauthenticated, "%v", err)
+		}
+		signer := NewBatchSigner(key, batchIDFromMetadata(md))
+		return handler(srv, &verifyingServerStream{ServerStream: stream, signer: signer})
+	}
+}
+
+// verifyingServerStream проверяет цепочку подписей и до io.EOF убеждается, что получена последняя метрика пачки
+type verifyingServerStream struct {
+	grpc.ServerStream
+	signer *BatchSigner
+	final  bool
+}
+
+func (s *verifyingServerStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if errors.Is(err, io.EOF) && !s.final {
+		return status.Error(codes.Unauthenticated, "batch is incomplete: last metric is not signed as final")
+	}
+	if err != nil {
+		return err
+	}
+
+	metric, ok := m.(*metricspb.Metric)
+	if !ok {
+		return nil
+	}
+	if s.final {
+		return status.Errorf(codes.Unauthenticated, "unexpected metric %s after the last one", metric.GetId())
+	}
+	final, ok := s.signer.Verify(metric)
+	if !ok {
+		return status.Errorf(codes.Unauthenticated, "invalid hash of metric %s", metric.GetId())
+	}
+	s.final = final
+	return nil
+}
@@ -0,0 +1,199 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/Bessima/metrics-collect/internal/common"
+	"github.com/Bessima/metrics-collect/internal/keyring"
+	"github.com/Bessima/metrics-collect/internal/metricspb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// fakeServerStream отдает сообщения по очереди, затем io.EOF, и контекст с заданными метаданными
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	messages []*metricspb.Metric
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeServerStream) RecvMsg(m any) error {
+	if len(s.messages) == 0 {
+		return io.EOF
+	}
+	message := s.messages[0]
+	s.messages = s.messages[1:]
+	*m.(*metricspb.Metric) = metricspb.Metric{Id: message.Id, Type: message.Type, Delta: message.Delta, Hash: message.Hash}
+	return nil
+}
+
+func recvHandler(_ any, stream grpc.ServerStream) error {
+	for {
+		err := stream.RecvMsg(&metricspb.Metric{})
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// signBatch подписывает метрики с идентификаторами ids как одну пачку
+func signBatch(t *testing.T, key string, batchID string, ids ...string) []*metricspb.Metric {
+	t.Helper()
+
+	signer := NewBatchSigner(key, batchID)
+	metrics := make([]*metricspb.Metric, 0, len(ids))
+	for i, id := range ids {
+		delta := int64(i + 1)
+		metric := &metricspb.Metric{Id: id, Type: metricspb.Metric_COUNTER, Delta: &delta}
+		require.NoError(t, signer.Sign(metric, i == len(ids)-1))
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func TestBatchSigner(t *testing.T) {
+	verify := func(key string, batchID string, metrics ...*metricspb.Metric) (bool, bool) {
+		signer := NewBatchSigner(key, batchID)
+		final := false
+		for _, metric := range metrics {
+			var ok bool
+			if final, ok = signer.Verify(metric); !ok {
+				return false, false
+			}
+		}
+		return final, true
+	}
+
+	metrics := signBatch(t, "secret", "batch-1", "first", "second", "third")
+	assert.NotEmpty(t, metrics[0].Hash)
+
+	final, ok := verify("secret", "batch-1", metrics...)
+	assert.True(t, ok)
+	assert.True(t, final)
+
+	// без последней метрики подписи сходятся, но пачка не завершена
+	final, ok = verify("secret", "batch-1", metrics[:2]...)
+	assert.True(t, ok)
+	assert.False(t, final)
+
+	_, ok = verify("other", "batch-1", metrics...)
+	assert.False(t, ok)
+	_, ok = verify("secret", "batch-2", metrics...)
+	assert.False(t, ok, "batch id is signed")
+	_, ok = verify("secret", "batch-1", metrics[0], metrics[2], metrics[1])
+	assert.False(t, ok, "order is signed")
+	_, ok = verify("secret", "batch-1", metrics[1], metrics[2])
+	assert.False(t, ok, "first metric is signed")
+
+	// метрику из другой пачки нельзя подставить, даже с той же подписью ключа
+	other := signBatch(t, "secret", "batch-2", "first", "second", "third")
+	_, ok = verify("secret", "batch-1", metrics[0], other[1], other[2])
+	assert.False(t, ok)
+
+	*metrics[0].Delta = 100
+	_, ok = verify("secret", "batch-1", metrics...)
+	assert.False(t, ok)
+}
+
+func TestHashStreamServerInterceptor(t *testing.T) {
+	delta := int64(1)
+	unsigned := &metricspb.Metric{Id: "PollCount", Type: metricspb.Metric_COUNTER, Delta: &delta}
+
+	keys, err := keyring.New("secret", []keyring.Key{{ID: "agent-1", Secret: "agent-secret"}})
+	require.NoError(t, err)
+	interceptor := HashStreamServerInterceptor(keys)
+	withMetadata := func(pairs ...string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(pairs...))
+	}
+	ctx := withMetadata(common.BatchIDHeader, "batch-1")
+	recv := func(ctx context.Context, messages ...*metricspb.Metric) error {
+		return interceptor(nil, &fakeServerStream{ctx: ctx, messages: messages}, nil, recvHandler)
+	}
+
+	assert.NoError(t, recv(ctx, signBatch(t, "secret", "batch-1", "PollCount", "Alloc")...))
+	assert.Equal(t, codes.Unauthenticated, status.Code(recv(ctx, unsigned)))
+
+	// пачка без последней метрики, пустая пачка и метрика после последней отклоняются
+	assert.Equal(t, codes.Unauthenticated, status.Code(recv(ctx, signBatch(t, "secret", "batch-1", "PollCount", "Alloc")[:1]...)))
+	assert.Equal(t, codes.Unauthenticated, status.Code(recv(ctx)))
+	batch := signBatch(t, "secret", "batch-1", "PollCount")
+	assert.Equal(t, codes.Unauthenticated, status.Code(recv(ctx, batch[0], batch[0])))
+
+	// подпись привязана к идентификатору пачки из метаданных
+	assert.Equal(t, codes.Unauthenticated, status.Code(recv(withMetadata(common.BatchIDHeader, "batch-2"), batch...)))
+
+	// с идентификатором ключа подпись проверяется ключом агента
+	withKeyID := func(keyID string) context.Context {
+		return withMetadata(common.BatchIDHeader, "batch-1", keyring.Header, keyID)
+	}
+	assert.Equal(t, codes.Unauthenticated, status.Code(recv(withKeyID("agent-1"), batch...)))
+
+	agentSigned := signBatch(t, "agent-secret", "batch-1", "PollCount")
+	assert.NoError(t, recv(withKeyID("agent-1"), agentSigned...))
+	assert.Equal(t, codes.Unauthenticated, status.Code(recv(withKeyID("agent-2"), agentSigned...)))
+
+	// без ключа сервер подписи не проверяет
+	noKeys, err := keyring.New("", nil)
+	require.NoError(t, err)
+	err = HashStreamServerInterceptor(noKeys)(nil, &fakeServerStream{ctx: ctx, messages: []*metricspb.Metric{unsigned}}, nil, recvHandler)
+	assert.NoError(t, err)
+}
+
+func TestTrustedSubnetStreamInterceptor(t *testing.T) {
+	subnets, err := common.ParseSubnets("10.0.0.0/8, 192.168.1.0/24")
+	require.NoError(t, err)
+
+	withPeer := func(ip string) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 5000}})
+	}
+	withRealIP := func(ctx context.Context, ip string) context.Context {
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(common.RealIPHeader, ip))
+	}
+	handler := func(any, grpc.ServerStream) error { return nil }
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		trustProxy bool
+		code       codes.Code
+	}{
+		{"trusted peer", withPeer("10.1.2.3"), false, codes.OK},
+		{"untrusted peer", withPeer("172.16.0.1"), false, codes.PermissionDenied},
+		{"trusted peer and real ip", withRealIP(withPeer("10.1.2.3"), "192.168.1.10"), false, codes.OK},
+		{"untrusted real ip", withRealIP(withPeer("10.1.2.3"), "192.168.2.10"), false, codes.PermissionDenied},
+		{"untrusted peer with real ip", withRealIP(withPeer("172.16.0.1"), "192.168.1.10"), false, codes.PermissionDenied},
+		{"proxy with trusted real ip", withRealIP(withPeer("172.16.0.1"), "192.168.1.10"), true, codes.OK},
+		{"proxy without real ip", withPeer("10.1.2.3"), true, codes.PermissionDenied},
+		{"no address", context.Background(), false, codes.PermissionDenied},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := TrustedSubnetStreamInterceptor(subnets, test.trustProxy)(nil, &fakeServerStream{ctx: test.ctx}, nil, handler)
+			assert.Equal(t, test.code, status.Code(err))
+		})
+	}
+
+	// без подсетей проверка отключена
+	assert.NoError(t, TrustedSubnetStreamInterceptor(nil, false)(nil, &fakeServerStream{ctx: withPeer("172.16.0.1")}, nil, handler))
+}
+
+func TestParseSubnets_Invalid(t *testing.T) {
+	_, err := common.ParseSubnets("10.0.0.0/8,10.0.0.1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,47 @@
+package interceptors
+
+import (
+	"context"
+	"net"
+
+	"github.com/Bessima/metrics-collect/internal/common"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// TrustedSubnetStreamInterceptor принимает потоки только от агентов из доверенных подсетей.
+// Адреса проверяются так же, как в HTTP-middleware subnet.TrustedSubnetMiddleware: адрес из метаданных
+// x-real-ip и адрес соединения, а с trustProxy — только x-real-ip. Пустой список подсетей отключает проверку.
+func TrustedSubnetStreamInterceptor(subnets []*net.IPNet, trustProxy bool) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := stream.Context()
+		if len(subnets) > 0 && !common.IsTrustedAgent(subnets, peerIP(ctx), realIP(ctx), trustProxy) {
+			return status.Error(codes.PermissionDenied, "agent is not in trusted subnet")
+		}
+		return handler(srv, stream)
+	}
+}
+
+func realIP(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(common.RealIPHeader); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+func peerIP(ctx context.Context) net.IP {
+	remote, ok := peer.FromContext(ctx)
+	if !ok || remote.Addr == nil {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(remote.Addr.String())
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
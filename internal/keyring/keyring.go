@@ -0,0 +1,123 @@
+// Package keyring хранит ключи HMAC агентов с идентификаторами и сроками действия
+package keyring
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Header заголовок с идентификатором ключа, которым подписан запрос
+const Header = "Key-Id"
+
+var (
+	ErrUnknownKey  = errors.New("unknown key id")
+	ErrInactiveKey = errors.New("key is not active")
+)
+
+// Key ключ агента. Пустые NotBefore и NotAfter не ограничивают срок действия.
+// Во время ротации новый ключ добавляется заранее, а старый действует до NotAfter,
+// поэтому сервер принимает оба ключа, пока агенты переходят на новый.
+type Key struct {
+	ID        string    `json:"id"`
+	Secret    string    `json:"secret"`
+	NotBefore time.Time `json:"not_before,omitempty"`
+	NotAfter  time.Time `json:"not_after,omitempty"`
+}
+
+// active действует ли ключ в момент now
+func (key Key) active(now time.Time) bool {
+	if !key.NotBefore.IsZero() && now.Before(key.NotBefore) {
+		return false
+	}
+	if !key.NotAfter.IsZero() && !now.Before(key.NotAfter) {
+		return false
+	}
+	return true
+}
+
+// file формат файла ключей
+type file struct {
+	Keys []Key `json:"keys"`
+}
+
+// KeyRing ключи агентов по идентификатору.
+// Запрос без идентификатора проверяется общим ключом, заданным флагом -k.
+type KeyRing struct {
+	defaultKey string
+	keys       map[string]Key
+	now        func() time.Time
+}
+
+func New(defaultKey string, keys []Key) (*KeyRing, error) {
+	ring := &KeyRing{
+		defaultKey: defaultKey,
+		keys:       make(map[string]Key, len(keys)),
+		now:        time.Now,
+	}
+
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("key id is empty")
+		}
+		if key.Secret == "" {
+			return nil, fmt.Errorf("secret of key %s is empty", key.ID)
+		}
+		if !key.NotBefore.IsZero() && !key.NotAfter.IsZero() && !key.NotBefore.Before(key.NotAfter) {
+			return nil, fmt.Errorf("key %s expires before it becomes active", key.ID)
+		}
+		if _, exists := ring.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key id %s", key.ID)
+		}
+		ring.keys[key.ID] = key
+	}
+	return ring, nil
+}
+
+// Load читает ключи из JSON-файла path, пустой path — только общий ключ
+func Load(path string, defaultKey string) (*KeyRing, error) {
+	if path == "" {
+		return New(defaultKey, nil)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key ring: %v", err)
+	}
+	var keys file
+	if err = json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse key ring %s: %v", path, err)
+	}
+
+	ring, err := New(defaultKey, keys.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key ring %s: %v", path, err)
+	}
+	return ring, nil
+}
+
+// Enabled задан ли хотя бы один ключ
+func (ring *KeyRing) Enabled() bool {
+	return ring != nil && (ring.defaultKey != "" || len(ring.keys) > 0)
+}
+
+// Secret возвращает действующий ключ с идентификатором id, для пустого id — общий ключ
+func (ring *KeyRing) Secret(id string) (string, error) {
+	if id == "" {
+		if ring.defaultKey == "" {
+			return "", ErrUnknownKey
+		}
+		return ring.defaultKey, nil
+	}
+
+	key, exists := ring.keys[id]
+	if !exists {
+		return "", fmt.Errorf("%w %s", ErrUnknownKey, id)
+	}
+	if !key.active(ring.now()) {
+		return "", fmt.Errorf("%w: %s", ErrInactiveKey, id)
+	}
+	return key.Secret, nil
+}
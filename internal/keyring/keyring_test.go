@@ -0,0 +1,81 @@
+package keyring
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRing_Rotation(t *testing.T) {
+	rotation := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	ring, err := New("default", []Key{
+		{ID: "agent-old", Secret: "old", NotAfter: rotation.Add(time.Hour)},
+		{ID: "agent-new", Secret: "new", NotBefore: rotation},
+	})
+	require.NoError(t, err)
+
+	// до ротации действует только старый ключ
+	ring.now = func() time.Time { return rotation.Add(-time.Minute) }
+	secret, err := ring.Secret("agent-old")
+	require.NoError(t, err)
+	assert.Equal(t, "old", secret)
+	_, err = ring.Secret("agent-new")
+	assert.ErrorIs(t, err, ErrInactiveKey)
+
+	// во время ротации принимаются оба ключа
+	ring.now = func() time.Time { return rotation.Add(time.Minute) }
+	for id, want := range map[string]string{"agent-old": "old", "agent-new": "new"} {
+		secret, err = ring.Secret(id)
+		require.NoError(t, err)
+		assert.Equal(t, want, secret)
+	}
+
+	ring.now = func() time.Time { return rotation.Add(time.Hour) }
+	_, err = ring.Secret("agent-old")
+	assert.ErrorIs(t, err, ErrInactiveKey)
+
+	_, err = ring.Secret("agent-unknown")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	secret, err = ring.Secret("")
+	require.NoError(t, err)
+	assert.Equal(t, "default", secret)
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	data := `{"keys":[{"id":"agent-1","secret":"s1","not_after":"2026-01-01T00:00:00Z"},{"id":"agent-2","secret":"s2"}]}`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+
+	ring, err := Load(path, "")
+	require.NoError(t, err)
+	assert.True(t, ring.Enabled())
+
+	_, err = ring.Secret("")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	secret, err := ring.Secret("agent-2")
+	require.NoError(t, err)
+	assert.Equal(t, "s2", secret)
+
+	ring, err = Load("", "")
+	require.NoError(t, err)
+	assert.False(t, ring.Enabled())
+}
+
+func TestNew_InvalidKeys(t *testing.T) {
+	now := time.Now()
+	invalid := [][]Key{
+		{{Secret: "s"}},
+		{{ID: "agent"}},
+		{{ID: "agent", Secret: "s"}, {ID: "agent", Secret: "s2"}},
+		{{ID: "agent", Secret: "s", NotBefore: now, NotAfter: now}},
+	}
+	for _, keys := range invalid {
+		_, err := New("", keys)
+		assert.Error(t, err)
+	}
+}
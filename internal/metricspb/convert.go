@@ -0,0 +1,33 @@
+package metricspb
+
+import (
+	"fmt"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+)
+
+// FromModel переводит метрику агента в сообщение gRPC
+func FromModel(metric models.Metrics) *Metric {
+	message := &Metric{Id: metric.ID, Delta: metric.Delta, Value: metric.Value, Hash: metric.Hash}
+	switch metric.MType {
+	case models.Gauge:
+		message.Type = Metric_GAUGE
+	case models.Counter:
+		message.Type = Metric_COUNTER
+	}
+	return message
+}
+
+// ToModel переводит сообщение gRPC в метрику, значения проверяются при сохранении
+func (x *Metric) ToModel() (models.Metrics, error) {
+	metric := models.Metrics{ID: x.GetId(), Delta: x.Delta, Value: x.Value, Hash: x.GetHash()}
+	switch x.GetType() {
+	case Metric_GAUGE:
+		metric.MType = models.Gauge
+	case Metric_COUNTER:
+		metric.MType = models.Counter
+	default:
+		return metric, fmt.Errorf("type %s not supported", x.GetType())
+	}
+	return metric, nil
+}
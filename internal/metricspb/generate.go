@@ -0,0 +1,4 @@
+// Package metricspb содержит код, сгенерированный по контракту gRPC api/proto/metrics.proto
+package metricspb
+
+//go:generate protoc --proto_path=../../api/proto --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative metrics.proto
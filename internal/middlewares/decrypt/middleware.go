@@ -0,0 +1,50 @@
+package decrypt
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"io"
+	"net/http"
+
+	"github.com/Bessima/metrics-collect/internal/encryption"
+	"github.com/Bessima/metrics-collect/internal/middlewares/logger"
+	"go.uber.org/zap"
+)
+
+// DecryptMiddleware расшифровывает тело запроса с заголовком encryption.Header закрытым ключом сервера.
+// Должен выполняться до compress.GZIPMiddleware: агент шифрует уже сжатое тело.
+// Без ключа зашифрованные запросы отклоняются, незашифрованные запросы передаются дальше без изменений.
+func DecryptMiddleware(privateKey *rsa.PrivateKey) func(handler http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme := r.Header.Get(encryption.Header)
+			if scheme == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if privateKey == nil || scheme != encryption.Scheme {
+				http.Error(w, "Encryption is not supported", http.StatusBadRequest)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "Failed to read request body", http.StatusBadRequest)
+				return
+			}
+
+			decrypted, err := encryption.Decrypt(privateKey, body)
+			if err != nil {
+				logger.Log.Warn("Failed to decrypt request body", zap.Error(err))
+				http.Error(w, "Failed to decrypt request body", http.StatusBadRequest)
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(decrypted))
+			r.ContentLength = int64(len(decrypted))
+			r.Header.Del(encryption.Header)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package decrypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bessima/metrics-collect/internal/encryption"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func echoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Has-Encryption", r.Header.Get(encryption.Header))
+		w.Write(body)
+	})
+}
+
+func TestDecryptMiddleware(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	handler := DecryptMiddleware(key)(echoHandler())
+
+	data := bytes.Repeat([]byte("gzip body"), 100)
+	encrypted, err := encryption.Encrypt(&key.PublicKey, data)
+	require.NoError(t, err)
+
+	t.Run("encrypted", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(encrypted))
+		req.Header.Set(encryption.Header, encryption.Scheme)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, data, rec.Body.Bytes())
+		assert.Empty(t, rec.Header().Get("X-Has-Encryption"))
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(data))
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, data, rec.Body.Bytes())
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(data))
+		req.Header.Set(encryption.Header, encryption.Scheme)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("unknown scheme", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(encrypted))
+		req.Header.Set(encryption.Header, "xor")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestDecryptMiddleware_WithoutKey(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader([]byte("data")))
+	req.Header.Set(encryption.Header, encryption.Scheme)
+	rec := httptest.NewRecorder()
+
+	DecryptMiddleware(nil)(echoHandler()).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
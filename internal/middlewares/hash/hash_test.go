@@ -6,8 +6,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/Bessima/metrics-collect/internal/common"
+	"github.com/Bessima/metrics-collect/internal/keyring"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -345,3 +347,84 @@ func TestHashCheckerMiddleware_Integration(t *testing.T) {
 	expectedResponseHash := common.GetHashData(rec.Body.Bytes(), keyHash)
 	assert.Equal(t, expectedResponseHash, responseHash)
 }
+
+func TestKeyRingCheckerMiddleware_KeyID(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("success"))
+	})
+
+	keys, err := keyring.New("", []keyring.Key{
+		{ID: "agent-old", Secret: "old-key", NotAfter: time.Now().Add(time.Hour)},
+		{ID: "agent-new", Secret: "new-key"},
+		{ID: "agent-expired", Secret: "expired-key", NotAfter: time.Now().Add(-time.Hour)},
+	})
+	require.NoError(t, err)
+	wrappedHandler := KeyRingCheckerMiddleware(keys)(handler)
+
+	requestBody := []byte(`{"data":"test"}`)
+	tests := []struct {
+		name     string
+		keyID    string
+		key      string
+		wantCode int
+	}{
+		{name: "old key during rotation", keyID: "agent-old", key: "old-key", wantCode: http.StatusOK},
+		{name: "new key", keyID: "agent-new", key: "new-key", wantCode: http.StatusOK},
+		{name: "key of another agent", keyID: "agent-new", key: "old-key", wantCode: http.StatusBadRequest},
+		{name: "expired key", keyID: "agent-expired", key: "expired-key", wantCode: http.StatusUnauthorized},
+		{name: "unknown key", keyID: "agent-unknown", key: "new-key", wantCode: http.StatusUnauthorized},
+		{name: "without key id", key: "new-key", wantCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(requestBody))
+			req.Header.Set(common.HashHeader, common.GetHashData(requestBody, tt.key))
+			if tt.keyID != "" {
+				req.Header.Set(keyring.Header, tt.keyID)
+			}
+			rec := httptest.NewRecorder()
+
+			wrappedHandler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantCode == http.StatusOK {
+				assert.Equal(t, tt.keyID, rec.Header().Get(keyring.Header))
+				assert.Equal(t, common.GetHashData(rec.Body.Bytes(), tt.key), rec.Header().Get(common.HashHeader))
+			}
+		})
+	}
+}
+
+func TestKeyRingCheckerMiddleware_UnsignedUpdate(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	keys, err := keyring.New("", []keyring.Key{{ID: "agent-1", Secret: "agent-secret"}})
+	require.NoError(t, err)
+	wrappedHandler := KeyRingCheckerMiddleware(keys)(handler)
+
+	// изменение метрик без подписи отклоняется
+	for _, path := range []string{"/updates/", "/update/", "/update/counter/PollCount/1"} {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(`[]`))
+		rec := httptest.NewRecorder()
+		wrappedHandler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, path)
+	}
+
+	// чтение метрик подписи не требует
+	req := httptest.NewRequest(http.MethodGet, "/value/counter/PollCount", nil)
+	rec := httptest.NewRecorder()
+	wrappedHandler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// без ключей подпись не проверяется
+	noKeys, err := keyring.New("", nil)
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBufferString(`[]`))
+	rec = httptest.NewRecorder()
+	KeyRingCheckerMiddleware(noKeys)(handler).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
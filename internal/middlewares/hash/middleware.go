@@ -5,9 +5,12 @@ import (
 	"crypto/hmac"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/Bessima/metrics-collect/internal/common"
+	"github.com/Bessima/metrics-collect/internal/keyring"
 	"github.com/Bessima/metrics-collect/internal/middlewares/logger"
+	"go.uber.org/zap"
 )
 
 type HashResponseWriter struct {
@@ -23,15 +26,46 @@ func (hw HashResponseWriter) Write(data []byte) (int, error) {
 	return hw.ResponseWriter.Write(data)
 }
 
+// HashCheckerMiddleware проверяет подпись запроса общим ключом keyHash
 func HashCheckerMiddleware(keyHash string) func(handler http.Handler) http.Handler {
+	// без файла ключей New не возвращает ошибку
+	keys, _ := keyring.New(keyHash, nil)
+	return KeyRingCheckerMiddleware(keys)
+}
+
+// updatePathPrefix подпись обязательна для запросов, изменяющих метрики: /update/ и /updates/
+const updatePathPrefix = "/update"
+
+// KeyRingCheckerMiddleware проверяет подпись запроса ключом из заголовка keyring.Header,
+// без заголовка — общим ключом. Если ключи заданы, запрос /update* без подписи отклоняется с кодом 401,
+// как и неподписанная метрика в gRPC. Тело хешируется в том виде, в котором его подписал агент,
+// поэтому middleware подключается до распаковки.
+func KeyRingCheckerMiddleware(keys *keyring.KeyRing) func(handler http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !keys.Enabled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			hash := r.Header.Get(common.HashHeader)
-			if keyHash == "" || hash == "" {
+			if hash == "" {
+				if strings.HasPrefix(r.URL.Path, updatePathPrefix) {
+					http.Error(w, "Request is not signed", http.StatusUnauthorized)
+					return
+				}
 				next.ServeHTTP(w, r)
 				return
 			}
 
+			keyID := r.Header.Get(keyring.Header)
+			keyHash, err := keys.Secret(keyID)
+			if err != nil {
+				logger.Log.Info("Rejected request signed with unknown key", zap.String("key_id", keyID), zap.Error(err))
+				http.Error(w, "Unknown or inactive key", http.StatusUnauthorized)
+				return
+			}
+
 			// Читаем тело запроса
 			body, err := io.ReadAll(r.Body)
 			if err != nil {
@@ -44,11 +78,14 @@ func HashCheckerMiddleware(keyHash string) func(handler http.Handler) http.Handl
 
 			serverHash := common.GetHashData(body, keyHash)
 
-			if hmac.Equal([]byte(hash), []byte(serverHash)) {
+			if !hmac.Equal([]byte(hash), []byte(serverHash)) {
 				http.Error(w, "Data was not transferred fully", http.StatusBadRequest)
 				return
 			}
 			logger.Log.Debug("Hashes are equal")
+			if keyID != "" {
+				w.Header().Set(keyring.Header, keyID)
+			}
 			hw := HashResponseWriter{ResponseWriter: w, keyHash: keyHash}
 
 			next.ServeHTTP(hw, r)
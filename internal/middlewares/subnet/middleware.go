@@ -0,0 +1,49 @@
+package subnet
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/Bessima/metrics-collect/internal/common"
+	"github.com/Bessima/metrics-collect/internal/middlewares/logger"
+	"go.uber.org/zap"
+)
+
+// updatePathPrefix проверяются только запросы, изменяющие метрики: /update/ и /updates/
+const updatePathPrefix = "/update"
+
+// TrustedSubnetMiddleware отклоняет запросы /update* с кодом 403, если агент не входит в доверенные подсети.
+// Без trustProxy проверяются адрес соединения и заголовок X-Real-IP, если он передан.
+// С trustProxy сервер стоит за обратным прокси и проверяется только обязательный заголовок X-Real-IP.
+// Пустой список подсетей отключает проверку.
+func TrustedSubnetMiddleware(subnets []*net.IPNet, trustProxy bool) func(handler http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(subnets) == 0 || !strings.HasPrefix(r.URL.Path, updatePathPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			realIP := r.Header.Get(common.RealIPHeader)
+			if !common.IsTrustedAgent(subnets, remoteIP(r.RemoteAddr), realIP, trustProxy) {
+				logger.Log.Warn(
+					"Request from untrusted subnet",
+					zap.String("remote_addr", r.RemoteAddr),
+					zap.String("real_ip", realIP),
+				)
+				http.Error(w, "Agent is not in trusted subnet", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func remoteIP(remoteAddr string) net.IP {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return net.ParseIP(remoteAddr)
+	}
+	return net.ParseIP(host)
+}
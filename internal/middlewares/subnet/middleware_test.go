@@ -0,0 +1,59 @@
+package subnet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bessima/metrics-collect/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustedSubnetMiddleware(t *testing.T) {
+	subnets, err := common.ParseSubnets("192.168.1.0/24")
+	require.NoError(t, err)
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {})
+
+	tests := []struct {
+		name       string
+		path       string
+		remoteAddr string
+		realIP     string
+		trustProxy bool
+		want       int
+	}{
+		{"trusted agent", "/updates/", "192.168.1.5:4000", "192.168.1.5", false, http.StatusOK},
+		{"trusted peer without header", "/update/", "192.168.1.5:4000", "", false, http.StatusOK},
+		{"untrusted header", "/updates/", "192.168.1.5:4000", "10.0.0.5", false, http.StatusForbidden},
+		{"untrusted peer", "/update/gauge/Alloc/1", "10.0.0.5:4000", "192.168.1.5", false, http.StatusForbidden},
+		{"behind proxy", "/updates/", "10.0.0.1:4000", "192.168.1.5", true, http.StatusOK},
+		{"behind proxy without header", "/updates/", "192.168.1.5:4000", "", true, http.StatusForbidden},
+		{"behind proxy untrusted header", "/updates/", "10.0.0.1:4000", "10.0.0.5", true, http.StatusForbidden},
+		{"reading is not restricted", "/value/", "10.0.0.5:4000", "", false, http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, test.path, nil)
+			req.RemoteAddr = test.remoteAddr
+			if test.realIP != "" {
+				req.Header.Set(common.RealIPHeader, test.realIP)
+			}
+			rec := httptest.NewRecorder()
+
+			TrustedSubnetMiddleware(subnets, test.trustProxy)(next).ServeHTTP(rec, req)
+
+			assert.Equal(t, test.want, rec.Code)
+		})
+	}
+}
+
+func TestTrustedSubnetMiddleware_Disabled(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/updates/", nil)
+	req.RemoteAddr = "10.0.0.5:4000"
+	rec := httptest.NewRecorder()
+
+	TrustedSubnetMiddleware(nil, false)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {})).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
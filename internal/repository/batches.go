@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	models "github.com/Bessima/metrics-collect/internal/model"
+)
+
+const (
+	defaultBatchTTL      = time.Hour
+	defaultBatchMaxCount = 100000
+)
+
+// BatchRegistry хранит идентификаторы уже примененных пачек метрик,
+// чтобы повторная доставка той же пачки не изменяла значения счетчиков
+type BatchRegistry interface {
+	// ClaimBatch атомарно занимает идентификатор пачки до ее применения,
+	// false — пачка уже применена или применяется параллельным запросом
+	ClaimBatch(batchID string) (bool, error)
+	// ReleaseBatch освобождает идентификатор пачки, которую не удалось применить
+	ReleaseBatch(batchID string) error
+}
+
+// BatchApplier хранилище, которое применяет пачку и запоминает ее идентификатор в одной транзакции
+type BatchApplier interface {
+	ApplyBatch(batchID string, metrics []models.Metrics) (duplicate bool, err error)
+}
+
+// MemBatchRegistry хранит идентификаторы пачек в памяти в течение ttl
+type MemBatchRegistry struct {
+	mutex    sync.Mutex
+	batches  map[string]time.Time
+	ttl      time.Duration
+	maxCount int
+}
+
+func NewMemBatchRegistry() *MemBatchRegistry {
+	return &MemBatchRegistry{
+		batches:  make(map[string]time.Time),
+		ttl:      defaultBatchTTL,
+		maxCount: defaultBatchMaxCount,
+	}
+}
+
+// ClaimBatch проверяет и запоминает идентификатор под одной блокировкой,
+// поэтому из параллельных запросов с одной пачкой ее займет только один
+func (registry *MemBatchRegistry) ClaimBatch(batchID string) (bool, error) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	now := time.Now()
+	if appliedAt, exists := registry.batches[batchID]; exists && now.Sub(appliedAt) < registry.ttl {
+		return false, nil
+	}
+	if len(registry.batches) >= registry.maxCount {
+		registry.purge(now)
+	}
+	registry.batches[batchID] = now
+	return true, nil
+}
+
+func (registry *MemBatchRegistry) ReleaseBatch(batchID string) error {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	delete(registry.batches, batchID)
+	return nil
+}
+
+// purge удаляет устаревшие идентификаторы, а если их недостаточно - самые старые
+func (registry *MemBatchRegistry) purge(now time.Time) {
+	var oldestID string
+	var oldestAt time.Time
+
+	for batchID, appliedAt := range registry.batches {
+		if now.Sub(appliedAt) >= registry.ttl {
+			delete(registry.batches, batchID)
+			continue
+		}
+		if oldestID == "" || appliedAt.Before(oldestAt) {
+			oldestID, oldestAt = batchID, appliedAt
+		}
+	}
+
+	if len(registry.batches) >= registry.maxCount && oldestID != "" {
+		delete(registry.batches, oldestID)
+	}
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemBatchRegistry_ClaimBatch(t *testing.T) {
+	registry := NewMemBatchRegistry()
+
+	claimed, err := registry.ClaimBatch("batch-1")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = registry.ClaimBatch("batch-1")
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	claimed, err = registry.ClaimBatch("batch-2")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
+
+func TestMemBatchRegistry_ReleaseBatch(t *testing.T) {
+	registry := NewMemBatchRegistry()
+
+	claimed, err := registry.ClaimBatch("batch-1")
+	require.NoError(t, err)
+	require.True(t, claimed)
+	require.NoError(t, registry.ReleaseBatch("batch-1"))
+
+	// пачку, которую не удалось применить, можно отправить снова
+	claimed, err = registry.ClaimBatch("batch-1")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
+
+func TestMemBatchRegistry_ConcurrentClaim(t *testing.T) {
+	registry := NewMemBatchRegistry()
+
+	var claims atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claimed, err := registry.ClaimBatch("batch-1")
+			assert.NoError(t, err)
+			if claimed {
+				claims.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(1), claims.Load())
+}
+
+func TestMemBatchRegistry_Expired(t *testing.T) {
+	registry := NewMemBatchRegistry()
+	registry.ttl = time.Millisecond
+
+	_, err := registry.ClaimBatch("batch-1")
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+
+	claimed, err := registry.ClaimBatch("batch-1")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
+
+func TestMemBatchRegistry_MaxCount(t *testing.T) {
+	registry := NewMemBatchRegistry()
+	registry.maxCount = 3
+
+	for i := 0; i < 10; i++ {
+		_, err := registry.ClaimBatch(fmt.Sprintf("batch-%d", i))
+		require.NoError(t, err)
+	}
+
+	assert.LessOrEqual(t, len(registry.batches), 3)
+
+	claimed, err := registry.ClaimBatch("batch-9")
+	require.NoError(t, err)
+	assert.False(t, claimed)
+}
+
+func TestStorages_ImplementBatchRegistry(t *testing.T) {
+	var _ BatchRegistry = NewMemStorage()
+	var _ BatchRegistry = NewFileStorageRepository(t.TempDir() + "/storage.json")
+	var _ BatchApplier = &DBRepository{}
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/Bessima/metrics-collect/internal/config/db"
 	"github.com/Bessima/metrics-collect/internal/middlewares/logger"
@@ -30,11 +31,14 @@ func NewDBRepository(rootContext context.Context, databaseDNS string) *DBReposit
 	return &DBRepository{db: dbObj}
 }
 
-func (repository *DBRepository) Counter(name string, value int64) error {
-	query := `INSERT INTO metrics (name, type, delta) VALUES ($1, $2, $3) ON CONFLICT (name, type) DO UPDATE SET delta = metrics.delta + EXCLUDED.delta`
+const (
+	counterQuery = `INSERT INTO metrics (name, type, delta) VALUES ($1, $2, $3) ON CONFLICT (name, type) DO UPDATE SET delta = metrics.delta + EXCLUDED.delta`
+	gaugeQuery   = "INSERT INTO metrics (name, type, value) VALUES ($1, $2, $3) ON CONFLICT (name, type) DO UPDATE SET value = EXCLUDED.value"
+)
 
+func (repository *DBRepository) Counter(name string, value int64) error {
 	return retry.DoRetry(context.Background(), func() error {
-		result, err := repository.db.Pool.Exec(context.Background(), query, name, TypeCounter, value)
+		result, err := repository.db.Pool.Exec(context.Background(), counterQuery, name, TypeCounter, value)
 		if err != nil {
 			return err
 		}
@@ -46,10 +50,8 @@ func (repository *DBRepository) Counter(name string, value int64) error {
 }
 
 func (repository *DBRepository) ReplaceGaugeMetric(name string, value float64) error {
-	query := "INSERT INTO metrics (name, type, value) VALUES ($1, $2, $3) ON CONFLICT (name, type) DO UPDATE SET value = EXCLUDED.value"
-
 	return retry.DoRetry(context.Background(), func() error {
-		result, err := repository.db.Pool.Exec(context.Background(), query, name, TypeGauge, value)
+		result, err := repository.db.Pool.Exec(context.Background(), gaugeQuery, name, TypeGauge, value)
 		if err != nil {
 			return err
 		}
@@ -155,6 +157,56 @@ func (repository *DBRepository) All() ([]models.Metrics, error) {
 	})
 }
 
+// ApplyBatch применяет пачку и запоминает ее идентификатор в одной транзакции.
+// Вставка идентификатора занимает пачку: параллельная транзакция с тем же идентификатором
+// ждет ее завершения и получает duplicate, а при ошибке идентификатор откатывается вместе с метриками.
+func (repository *DBRepository) ApplyBatch(batchID string, metrics []models.Metrics) (bool, error) {
+	ctx := context.Background()
+
+	return retry.DoRetryWithResult(ctx, func() (bool, error) {
+		tx, err := repository.db.Pool.Begin(ctx)
+		if err != nil {
+			return false, err
+		}
+		// после Commit откат ничего не делает
+		defer tx.Rollback(ctx)
+
+		expiredAt := time.Now().Add(-defaultBatchTTL)
+		result, err := tx.Exec(
+			ctx,
+			"INSERT INTO applied_batches (batch_id) VALUES ($1)"+
+				" ON CONFLICT (batch_id) DO UPDATE SET applied_at = now() WHERE applied_batches.applied_at < $2",
+			batchID,
+			expiredAt,
+		)
+		if err != nil {
+			return false, err
+		}
+		if result.RowsAffected() == 0 {
+			return true, nil
+		}
+
+		for _, metric := range metrics {
+			switch {
+			case TypeMetric(metric.MType) == TypeCounter && metric.Delta != nil:
+				_, err = tx.Exec(ctx, counterQuery, metric.ID, TypeCounter, *metric.Delta)
+			case TypeMetric(metric.MType) == TypeGauge && metric.Value != nil:
+				_, err = tx.Exec(ctx, gaugeQuery, metric.ID, TypeGauge, *metric.Value)
+			default:
+				err = fmt.Errorf("invalid metric %s of type %s", metric.ID, metric.MType)
+			}
+			if err != nil {
+				return false, err
+			}
+		}
+
+		if _, err = tx.Exec(ctx, "DELETE FROM applied_batches WHERE applied_at < $1", expiredAt); err != nil {
+			return false, err
+		}
+		return false, tx.Commit(ctx)
+	})
+}
+
 func (repository *DBRepository) Ping(ctx context.Context) error {
 	return retry.DoRetry(ctx, func() error {
 		return repository.db.Pool.Ping(ctx)
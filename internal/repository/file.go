@@ -59,6 +59,8 @@ func (metrics *MetricsFromFile) GetMetrics() []models.Metrics {
 }
 
 type FileStorageRepository struct {
+	*MemBatchRegistry
+
 	FileName string
 }
 
@@ -73,7 +75,7 @@ func NewFileStorageRepository(filename string) *FileStorageRepository {
 	}
 	defer file.Close()
 
-	return &FileStorageRepository{FileName: filename}
+	return &FileStorageRepository{MemBatchRegistry: NewMemBatchRegistry(), FileName: filename}
 }
 
 func (repository *FileStorageRepository) Counter(name string, value int64) error {
@@ -16,6 +16,8 @@ const (
 )
 
 type MemStorage struct {
+	*MemBatchRegistry
+
 	mutex    sync.RWMutex
 	counters map[string]models.Metrics
 	gauge    map[string]models.Metrics
@@ -23,8 +25,9 @@ type MemStorage struct {
 
 func NewMemStorage() *MemStorage {
 	return &MemStorage{
-		counters: make(map[string]models.Metrics),
-		gauge:    make(map[string]models.Metrics),
+		MemBatchRegistry: NewMemBatchRegistry(),
+		counters:         make(map[string]models.Metrics),
+		gauge:            make(map[string]models.Metrics),
 	}
 }
 
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/Bessima/metrics-collect/internal/common"
+	"github.com/Bessima/metrics-collect/internal/handler"
+	"github.com/Bessima/metrics-collect/internal/interceptors"
+	"github.com/Bessima/metrics-collect/internal/keyring"
+	"github.com/Bessima/metrics-collect/internal/metricspb"
+	models "github.com/Bessima/metrics-collect/internal/model"
+	"github.com/Bessima/metrics-collect/internal/repository"
+	"github.com/Bessima/metrics-collect/pkg/audit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCService принимает метрики по gRPC параллельно с HTTP-сервером ServerService
+type GRPCService struct {
+	metricspb.UnimplementedMetricsServer
+
+	Server          *grpc.Server
+	address         string
+	storage         repository.StorageRepositorier
+	metricsFromFile *repository.MetricsFromFile
+	auditEvent      *audit.Event
+}
+
+// NewGRPCService создает gRPC-сервер, с tlsConfig — работающий по TLS
+func NewGRPCService(address string, keys *keyring.KeyRing, trustedSubnets []*net.IPNet, trustProxy bool, tlsConfig *tls.Config, storage repository.StorageRepositorier) *GRPCService {
+	grpcService := &GRPCService{address: address, storage: storage}
+
+	options := []grpc.ServerOption{grpc.ChainStreamInterceptor(
+		interceptors.TrustedSubnetStreamInterceptor(trustedSubnets, trustProxy),
+		interceptors.HashStreamServerInterceptor(keys),
+	)}
+	if tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	grpcService.Server = grpc.NewServer(options...)
+	metricspb.RegisterMetricsServer(grpcService.Server, grpcService)
+
+	return grpcService
+}
+
+// SetHandlers задает сохранение в файл и аудит так же, как ServerService.SetRouter
+func (grpcService *GRPCService) SetHandlers(storeInterval int64, metricsFromFile *repository.MetricsFromFile, auditEvent *audit.Event) {
+	if storeInterval == 0 {
+		grpcService.metricsFromFile = metricsFromFile
+	}
+	grpcService.auditEvent = auditEvent
+}
+
+// UpdateMetrics сохраняет пачку метрик, полученную потоком, после его завершения
+func (grpcService *GRPCService) UpdateMetrics(stream metricspb.Metrics_UpdateMetricsServer) error {
+	metrics := make([]models.Metrics, 0)
+	metricsNames := make([]string, 0)
+
+	for {
+		message, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		metric, err := message.ToModel()
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		metrics = append(metrics, metric)
+		metricsNames = append(metricsNames, metric.ID)
+	}
+
+	batchID := ""
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		if values := md.Get(common.BatchIDHeader); len(values) > 0 {
+			batchID = values[0]
+		}
+	}
+
+	duplicate, err := handler.ApplyBatch(grpcService.storage, batchID, metrics)
+	if duplicate {
+		return stream.SendAndClose(&metricspb.UpdateMetricsResponse{})
+	}
+	if err != nil {
+		if handler.IsInvalidBatch(err) {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		return status.Error(codes.Unavailable, err.Error())
+	}
+
+	if grpcService.metricsFromFile != nil {
+		repository.UpdateMetricInFile(grpcService.storage, grpcService.metricsFromFile)
+	}
+
+	if grpcService.auditEvent != nil {
+		remoteAddr, agentName := "", ""
+		if remote, ok := peer.FromContext(stream.Context()); ok {
+			remoteAddr = remote.Addr.String()
+			if tlsInfo, ok := remote.AuthInfo.(credentials.TLSInfo); ok {
+				agentName = common.PeerIdentity(&tlsInfo.State)
+			}
+		}
+		grpcService.auditEvent.Notify(metricsNames, remoteAddr, agentName)
+	}
+
+	return stream.SendAndClose(&metricspb.UpdateMetricsResponse{Applied: int64(len(metrics))})
+}
+
+func (grpcService *GRPCService) RunServer(serverErr *chan error) {
+	listener, err := net.Listen("tcp", grpcService.address)
+	if err != nil {
+		*serverErr <- err
+		return
+	}
+
+	if err = grpcService.Server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		*serverErr <- err
+	} else {
+		*serverErr <- nil
+	}
+}
+
+func (grpcService *GRPCService) Shutdown() {
+	grpcService.Server.GracefulStop()
+}
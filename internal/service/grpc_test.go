@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Bessima/metrics-collect/internal/agent"
+	"github.com/Bessima/metrics-collect/internal/common"
+	"github.com/Bessima/metrics-collect/internal/keyring"
+	models "github.com/Bessima/metrics-collect/internal/model"
+	"github.com/Bessima/metrics-collect/internal/repository"
+	"github.com/Bessima/metrics-collect/pkg/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runGRPCService(t *testing.T, hashKey string, trustedSubnet string, storage repository.StorageRepositorier) string {
+	t.Helper()
+
+	keys, err := keyring.New(hashKey, nil)
+	require.NoError(t, err)
+	return runGRPCServiceWithKeys(t, keys, trustedSubnet, storage)
+}
+
+func runGRPCServiceWithKeys(t *testing.T, keys *keyring.KeyRing, trustedSubnet string, storage repository.StorageRepositorier) string {
+	t.Helper()
+
+	trustedSubnets, err := common.ParseSubnets(trustedSubnet)
+	require.NoError(t, err)
+
+	grpcService := NewGRPCService("", keys, trustedSubnets, false, nil, storage)
+	grpcService.SetHandlers(300, nil, &audit.Event{})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go grpcService.Server.Serve(listener)
+	t.Cleanup(grpcService.Shutdown)
+
+	return listener.Addr().String()
+}
+
+func TestGRPCService_UpdateMetrics(t *testing.T) {
+	storage := repository.NewMemStorage()
+	address := runGRPCService(t, "secret", "", storage)
+
+	client, err := agent.NewGRPCClient(address, "secret", "", nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	metrics := []models.Metrics{
+		agent.NewCounter("PollCount", 3),
+		agent.NewGauge("Alloc", 1.5),
+	}
+	require.NoError(t, client.SendMetrics(context.Background(), "batch-1", metrics))
+
+	counter, err := storage.GetValue(repository.TypeCounter, "PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), counter)
+	gauge, err := storage.GetValue(repository.TypeGauge, "Alloc")
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, gauge)
+}
+
+func TestGRPCService_RejectsWrongKey(t *testing.T) {
+	storage := repository.NewMemStorage()
+	address := runGRPCService(t, "secret", "", storage)
+
+	client, err := agent.NewGRPCClient(address, "other", "", nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	err = client.SendMetrics(context.Background(), "", []models.Metrics{agent.NewCounter("PollCount", 3)})
+	assert.ErrorContains(t, err, "invalid hash")
+	assert.ErrorIs(t, err, agent.ErrBatchRejected)
+
+	_, err = storage.GetValue(repository.TypeCounter, "PollCount")
+	assert.Error(t, err)
+}
+
+func TestGRPCService_RejectsUntrustedSubnet(t *testing.T) {
+	storage := repository.NewMemStorage()
+	address := runGRPCService(t, "", "10.0.0.0/8", storage)
+
+	client, err := agent.NewGRPCClient(address, "", "", nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	err = client.SendMetrics(context.Background(), "", []models.Metrics{agent.NewGauge("Alloc", 1)})
+	assert.ErrorContains(t, err, "trusted subnet")
+}
+
+func TestGRPCService_InvalidMetric(t *testing.T) {
+	storage := repository.NewMemStorage()
+	address := runGRPCService(t, "", "", storage)
+
+	client, err := agent.NewGRPCClient(address, "", "", nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	err = client.SendMetrics(context.Background(), "", []models.Metrics{{ID: "Alloc", MType: models.Gauge}})
+	assert.ErrorContains(t, err, "value not found")
+}
+
+func TestGRPCService_KeyRing(t *testing.T) {
+	keys, err := keyring.New("", []keyring.Key{
+		{ID: "agent-old", Secret: "old", NotAfter: time.Now().Add(time.Hour)},
+		{ID: "agent-new", Secret: "new"},
+	})
+	require.NoError(t, err)
+	storage := repository.NewMemStorage()
+	address := runGRPCServiceWithKeys(t, keys, "", storage)
+
+	// во время ротации принимаются оба ключа
+	for keyID, key := range map[string]string{"agent-old": "old", "agent-new": "new"} {
+		client, err := agent.NewGRPCClient(address, key, keyID, nil)
+		require.NoError(t, err)
+		assert.NoError(t, client.SendMetrics(context.Background(), "", []models.Metrics{agent.NewCounter("PollCount", 1)}))
+		client.Close()
+	}
+
+	client, err := agent.NewGRPCClient(address, "old", "agent-new", nil)
+	require.NoError(t, err)
+	defer client.Close()
+	err = client.SendMetrics(context.Background(), "", []models.Metrics{agent.NewCounter("PollCount", 1)})
+	assert.ErrorContains(t, err, "invalid hash")
+
+	counter, err := storage.GetValue(repository.TypeCounter, "PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), counter)
+}
+
+// failingStorage имитирует недоступное хранилище
+type failingStorage struct {
+	*repository.MemStorage
+}
+
+func (storage failingStorage) ReplaceGaugeMetric(string, float64) error {
+	return errors.New("connection refused")
+}
+
+func TestGRPCService_StorageFailure(t *testing.T) {
+	address := runGRPCService(t, "", "", failingStorage{repository.NewMemStorage()})
+
+	client, err := agent.NewGRPCClient(address, "", "", nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	// после сбоя хранилища агент должен сохранить пачку и отправить ее снова
+	err = client.SendMetrics(context.Background(), "batch-1", []models.Metrics{agent.NewGauge("Alloc", 1)})
+	assert.ErrorContains(t, err, "connection refused")
+	assert.NotErrorIs(t, err, agent.ErrBatchRejected)
+}
@@ -2,23 +2,33 @@ package service
 
 import (
 	"context"
+	"crypto/rsa"
+	"crypto/tls"
 	"net"
 	"net/http"
 	"time"
 
 	"github.com/Bessima/metrics-collect/internal/handler"
+	"github.com/Bessima/metrics-collect/internal/keyring"
 	"github.com/Bessima/metrics-collect/internal/middlewares/compress"
+	"github.com/Bessima/metrics-collect/internal/middlewares/decrypt"
 	hashMiddleware "github.com/Bessima/metrics-collect/internal/middlewares/hash"
 	"github.com/Bessima/metrics-collect/internal/middlewares/logger"
+	"github.com/Bessima/metrics-collect/internal/middlewares/subnet"
 	"github.com/Bessima/metrics-collect/internal/repository"
 	"github.com/Bessima/metrics-collect/pkg/audit"
 	"github.com/go-chi/chi/v5"
 )
 
 type ServerService struct {
-	Server  *http.Server
-	storage repository.StorageRepositorier
-	hashKey string
+	Server     *http.Server
+	storage    repository.StorageRepositorier
+	hashKey    string
+	keys       *keyring.KeyRing
+	privateKey *rsa.PrivateKey
+
+	trustedSubnets []*net.IPNet
+	trustProxy     bool
 }
 
 func NewServerService(rootContext context.Context, address string, hashKey string, storage repository.StorageRepositorier) ServerService {
@@ -31,6 +41,27 @@ func NewServerService(rootContext context.Context, address string, hashKey strin
 	return ServerService{Server: server, storage: storage, hashKey: hashKey}
 }
 
+// SetTLSConfig включает TLS, вызывается до RunServer
+func (serverService *ServerService) SetTLSConfig(tlsConfig *tls.Config) {
+	serverService.Server.TLSConfig = tlsConfig
+}
+
+// SetTrustedSubnets ограничивает изменение метрик агентами из доверенных подсетей, вызывается до SetRouter
+func (serverService *ServerService) SetTrustedSubnets(trustedSubnets []*net.IPNet, trustProxy bool) {
+	serverService.trustedSubnets = trustedSubnets
+	serverService.trustProxy = trustProxy
+}
+
+// SetKeyRing задает ключи агентов вместо общего ключа hashKey, вызывается до SetRouter
+func (serverService *ServerService) SetKeyRing(keys *keyring.KeyRing) {
+	serverService.keys = keys
+}
+
+// SetPrivateKey задает ключ для расшифровки запросов агента, вызывается до SetRouter
+func (serverService *ServerService) SetPrivateKey(privateKey *rsa.PrivateKey) {
+	serverService.privateKey = privateKey
+}
+
 func (serverService *ServerService) SetRouter(storeInterval int64, metricsFromFile *repository.MetricsFromFile, auditEvent *audit.Event) {
 	var router chi.Router
 
@@ -47,8 +78,16 @@ func (serverService *ServerService) getRouter(metricsFromFile *repository.Metric
 	router := chi.NewRouter()
 
 	router.Use(logger.RequestLogger)
+	router.Use(subnet.TrustedSubnetMiddleware(serverService.trustedSubnets, serverService.trustProxy))
+	// агент шифрует сжатое тело, поэтому расшифровка выполняется до распаковки
+	router.Use(decrypt.DecryptMiddleware(serverService.privateKey))
+	// агент подписывает сжатое тело, поэтому подпись проверяется до распаковки
+	if serverService.keys != nil {
+		router.Use(hashMiddleware.KeyRingCheckerMiddleware(serverService.keys))
+	} else {
+		router.Use(hashMiddleware.HashCheckerMiddleware(serverService.hashKey))
+	}
 	router.Use(compress.GZIPMiddleware)
-	router.Use(hashMiddleware.HashCheckerMiddleware(serverService.hashKey))
 
 	templates := handler.ParseAllTemplates()
 	router.Get("/", handler.MainHandler(serverService.storage, templates))
@@ -67,7 +106,15 @@ func (serverService *ServerService) getRouter(metricsFromFile *repository.Metric
 }
 
 func (serverService *ServerService) RunServer(serverErr *chan error) {
-	if err := serverService.Server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	var err error
+	if serverService.Server.TLSConfig != nil {
+		// сертификат уже загружен в TLSConfig
+		err = serverService.Server.ListenAndServeTLS("", "")
+	} else {
+		err = serverService.Server.ListenAndServe()
+	}
+
+	if err != nil && err != http.ErrServerClosed {
 		*serverErr <- err
 	} else {
 		*serverErr <- nil
@@ -1,12 +1,15 @@
 package service
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/Bessima/metrics-collect/internal/common"
 	"github.com/Bessima/metrics-collect/internal/repository"
 	"github.com/Bessima/metrics-collect/pkg/audit"
 	"github.com/stretchr/testify/assert"
@@ -121,3 +124,38 @@ func TestServerService_WithoutHashKey(t *testing.T) {
 	assert.Equal(t, "", serverService.hashKey)
 	assert.NotNil(t, serverService.Server.Handler)
 }
+
+func TestServerService_SignedCompressedBatch(t *testing.T) {
+	hashKey := "my-secret-key"
+	storage := repository.NewMemStorage()
+
+	serverService := NewServerService(context.Background(), "localhost:8080", hashKey, storage)
+	serverService.SetRouter(300, nil, &audit.Event{})
+
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	_, err := gz.Write([]byte(`[{"id":"PollCount","type":"counter","delta":3}]`))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	send := func(hash string) int {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(body.Bytes()))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		req.Header.Set(common.HashHeader, hash)
+		rec := httptest.NewRecorder()
+		serverService.Server.Handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// подпись другим ключом отклоняется, метрики не применяются
+	assert.Equal(t, http.StatusBadRequest, send(common.GetHashData(body.Bytes(), "other-key")))
+	_, err = storage.GetValue(repository.TypeCounter, "PollCount")
+	assert.Error(t, err)
+
+	// агент подписывает сжатое тело
+	assert.Equal(t, http.StatusOK, send(common.GetHashData(body.Bytes(), hashKey)))
+	value, err := storage.GetValue(repository.TypeCounter, "PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), value)
+}
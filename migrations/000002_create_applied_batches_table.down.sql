@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_applied_batches_applied_at;
+DROP TABLE IF EXISTS applied_batches;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS applied_batches (
+                         batch_id VARCHAR(64) PRIMARY KEY,
+                         applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Индекс для очистки устаревших идентификаторов
+CREATE INDEX idx_applied_batches_applied_at ON applied_batches(applied_at);
@@ -55,7 +55,7 @@ func TestFileSubscriber_notify(t *testing.T) {
 	ip := "192.168.1.1"
 	ts := 1234567890
 
-	err := subscriber.notify(metrics, ip, ts)
+	err := subscriber.notify(AuditEventDTO{TS: ts, Metrics: metrics, IPAddress: ip})
 	require.NoError(t, err)
 
 	// Verify file contains data
@@ -79,11 +79,11 @@ func TestFileSubscriber_notify_MultipleWrites(t *testing.T) {
 	require.NotNil(t, subscriber)
 
 	// First write
-	err := subscriber.notify([]string{"metric1"}, "10.0.0.1", 1000)
+	err := subscriber.notify(AuditEventDTO{TS: 1000, Metrics: []string{"metric1"}, IPAddress: "10.0.0.1"})
 	require.NoError(t, err)
 
 	// Second write
-	err = subscriber.notify([]string{"metric2"}, "10.0.0.2", 2000)
+	err = subscriber.notify(AuditEventDTO{TS: 2000, Metrics: []string{"metric2"}, IPAddress: "10.0.0.2"})
 	require.NoError(t, err)
 
 	// Verify file contains both writes
@@ -144,7 +144,7 @@ func TestURLSubscriber_notify_Success(t *testing.T) {
 	ip := "127.0.0.1"
 	ts := 1234567890
 
-	err := subscriber.notify(metrics, ip, ts)
+	err := subscriber.notify(AuditEventDTO{TS: ts, Metrics: metrics, IPAddress: ip})
 	assert.NoError(t, err)
 }
 
@@ -163,7 +163,7 @@ func TestURLSubscriber_notify_ServerError(t *testing.T) {
 	ip := "127.0.0.1"
 	ts := 1234567890
 
-	err := subscriber.notify(metrics, ip, ts)
+	err := subscriber.notify(AuditEventDTO{TS: ts, Metrics: metrics, IPAddress: ip})
 	assert.Error(t, err)
 }
 
@@ -176,7 +176,7 @@ func TestURLSubscriber_notify_NetworkError(t *testing.T) {
 	ip := "127.0.0.1"
 	ts := 1234567890
 
-	err := subscriber.notify(metrics, ip, ts)
+	err := subscriber.notify(AuditEventDTO{TS: ts, Metrics: metrics, IPAddress: ip})
 	assert.Error(t, err)
 }
 
@@ -241,7 +241,7 @@ func TestEvent_Notify(t *testing.T) {
 	metrics := []string{"counter1", "gauge1"}
 	ip := "192.168.1.100"
 
-	event.Notify(metrics, ip)
+	event.Notify(metrics, ip, "agent-1")
 
 	// Verify file was written
 	data, err := os.ReadFile(filename)
@@ -250,6 +250,7 @@ func TestEvent_Notify(t *testing.T) {
 	assert.Contains(t, string(data), "counter1")
 	assert.Contains(t, string(data), "gauge1")
 	assert.Contains(t, string(data), "192.168.1.100")
+	assert.Contains(t, string(data), `"agent":"agent-1"`)
 }
 
 func TestEvent_Notify_MultipleObservers(t *testing.T) {
@@ -274,7 +275,7 @@ func TestEvent_Notify_MultipleObservers(t *testing.T) {
 	metrics := []string{"metric_test"}
 	ip := "10.20.30.40"
 
-	event.Notify(metrics, ip)
+	event.Notify(metrics, ip, "")
 
 	// Verify file was written
 	data, err := os.ReadFile(filename)
@@ -290,7 +291,7 @@ func TestEvent_Notify_NoObservers(t *testing.T) {
 	ip := "192.168.1.1"
 
 	// Should not panic with no observers
-	event.Notify(metrics, ip)
+	event.Notify(metrics, ip, "")
 }
 
 func TestEvent_Notify_EmptyMetrics(t *testing.T) {
@@ -305,7 +306,7 @@ func TestEvent_Notify_EmptyMetrics(t *testing.T) {
 	metrics := []string{}
 	ip := "192.168.1.1"
 
-	event.Notify(metrics, ip)
+	event.Notify(metrics, ip, "")
 
 	// Verify file was written even with empty metrics
 	data, err := os.ReadFile(filename)
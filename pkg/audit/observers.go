@@ -16,7 +16,7 @@ import (
 )
 
 type Observer interface {
-	notify(metrics []string, ip string, ts int) error
+	notify(event AuditEventDTO) error
 	getName() string
 }
 
@@ -40,8 +40,7 @@ func NewFileSubscriber(filename string) *FileSubscriber {
 	return &FileSubscriber{filename: filename}
 }
 
-func (observer *FileSubscriber) notify(metrics []string, ip string, ts int) error {
-	event := AuditEventDTO{TS: ts, Metrics: metrics, IPAddress: ip}
+func (observer *FileSubscriber) notify(event AuditEventDTO) error {
 	data, err := json.Marshal(event)
 	if err != nil {
 		return err
@@ -86,8 +85,7 @@ func (observer *URLSubscriber) getName() string {
 	return "url"
 }
 
-func (observer *URLSubscriber) notify(metrics []string, ip string, ts int) error {
-	event := AuditEventDTO{TS: ts, Metrics: metrics, IPAddress: ip}
+func (observer *URLSubscriber) notify(event AuditEventDTO) error {
 	data, err := json.Marshal(event)
 	if err != nil {
 		return err
@@ -143,10 +141,11 @@ func (e *Event) Register(o Observer) {
 	e.observers[o.getName()] = o
 }
 
-func (e *Event) Notify(metrics []string, ip string) {
-	ts := int(time.Now().Unix())
+// Notify передает наблюдателям событие об изменении метрик, agent может быть пустым
+func (e *Event) Notify(metrics []string, ip string, agent string) {
+	event := AuditEventDTO{TS: int(time.Now().Unix()), Metrics: metrics, IPAddress: ip, Agent: agent}
 	for _, observer := range e.observers {
-		err := observer.notify(metrics, ip, ts)
+		err := observer.notify(event)
 		if err != nil {
 			logger.Log.Error(err.Error())
 			continue
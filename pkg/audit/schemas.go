@@ -4,4 +4,6 @@ type AuditEventDTO struct {
 	TS        int      `json:"ts"`
 	Metrics   []string `json:"metrics"`
 	IPAddress string   `json:"ip_address"`
+	// Agent CN клиентского сертификата агента, если сервер проверяет сертификаты
+	Agent string `json:"agent,omitempty"`
 }